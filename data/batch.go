@@ -0,0 +1,122 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ChunkResult reports how one statement of a chunked write went
+type ChunkResult struct {
+	Offset       int // Index of the chunk's first row
+	Rows         int // Number of rows in the chunk
+	RowsAffected int64
+	Err          error
+}
+
+// BatchResult reports how a write that was split into several statements went
+type BatchResult struct {
+	Chunks       []ChunkResult
+	RowsAffected int64 // Total across the chunks that took effect
+}
+
+// Err combines the errors of the chunks that failed, each labelled with the rows it covered, or
+// returns nil if none did
+func (r BatchResult) Err() error {
+	if len(r.Chunks) == 1 {
+		return r.Chunks[0].Err
+	}
+	var errs []error
+	for _, chunk := range r.Chunks {
+		if chunk.Err != nil {
+			errs = append(errs, fmt.Errorf("rows %d to %d: %w", chunk.Offset, chunk.Offset+chunk.Rows-1, chunk.Err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// UpsertBatch upserts rows as UpsertWith does and reports on each statement it took.
+//
+// Rows are written in chunks small enough to stay under the dialect's limit on bind parameters, or
+// of options.ChunkSize rows. A failed chunk doesn't stop the others unless options.Atomic is set, in
+// which case every chunk is written in one transaction and the first failure rolls them all back.
+//
+// E.g.,
+//
+//	result, err := dao.UpsertBatch(data.UpsertOptions{ChunkSize: 5000}, rows...)
+//	for _, chunk := range result.Chunks {
+//		if chunk.Err != nil {
+//			log.Printf("rows %d to %d: %v", chunk.Offset, chunk.Offset+chunk.Rows-1, chunk.Err)
+//		}
+//	}
+func (dao *DAO[T]) UpsertBatch(options UpsertOptions, rows ...map[string]any) (BatchResult, error) {
+	return dao.writeChunks("upsert", rows, options.ChunkSize, options.Atomic,
+		func(dao *DAO[T], rows []map[string]any) (string, []any) {
+			return dao.upsertStatement(options, rows)
+		})
+}
+
+// writeChunks writes rows with statement, chunkSize rows at a time
+func (dao *DAO[T]) writeChunks(action string, rows []map[string]any, chunkSize int, atomic bool,
+	statement func(dao *DAO[T], rows []map[string]any) (string, []any)) (BatchResult, error) {
+	var result BatchResult
+	if len(rows) == 0 {
+		return result, nil
+	}
+	if chunkSize <= 0 {
+		chunkSize = dao.chunkSize(rows[0], statement)
+	}
+
+	write := func(dao *DAO[T]) error {
+		for offset := 0; offset < len(rows); offset += chunkSize {
+			chunkRows := rows[offset:min(offset+chunkSize, len(rows))]
+			chunk := ChunkResult{Offset: offset, Rows: len(chunkRows)}
+
+			query, args := statement(dao, chunkRows)
+			executed, err := dao.ISession.Exec(query, args...)
+			if err != nil {
+				chunk.Err = fmt.Errorf("%s failed: %w", action, dao.statementError(query, err))
+			} else if chunk.RowsAffected, err = executed.RowsAffected(); err != nil {
+				chunk.Err = fmt.Errorf("%s: reading rows affected: %w", action, err)
+			}
+			result.Chunks = append(result.Chunks, chunk)
+			result.RowsAffected += chunk.RowsAffected
+
+			if chunk.Err != nil && atomic {
+				return chunk.Err
+			}
+		}
+		return nil
+	}
+
+	if !atomic || len(rows) <= chunkSize {
+		err := write(dao)
+		if err == nil {
+			err = result.Err()
+		}
+		return result, err
+	}
+
+	err := inTransaction(dao.ISession, func(tx ISession) error {
+		txDAO := *dao
+		txDAO.ISession = tx
+		return write(&txDAO)
+	})
+	if err != nil {
+		result.RowsAffected = 0 // Rolled back
+		if chunksErr := result.Err(); chunksErr != nil {
+			return result, chunksErr
+		}
+		return result, err
+	}
+	return result, nil
+}
+
+// chunkSize returns how many rows like row fit in one statement under the dialect's parameter limit
+func (dao *DAO[T]) chunkSize(row map[string]any, statement func(dao *DAO[T], rows []map[string]any) (string, []any)) int {
+	perRow := max(len(row), 1)
+	// Arguments beyond the rows' own, e.g. an upsert's WHERE guard, are bound once per statement
+	_, args := statement(dao, []map[string]any{row})
+	overhead := len(args) - len(row)
+
+	return max((dao.ISession.Dialect().MaxParameters()-overhead)/perRow, 1)
+}
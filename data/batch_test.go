@@ -0,0 +1,135 @@
+package data
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"gormless/data/dialect"
+	"regexp"
+	"testing"
+)
+
+func batchRows(n int) []map[string]any {
+	rows := make([]map[string]any, n)
+	for i := range rows {
+		rows[i] = map[string]any{"user_id": i + 1, "user_first": "name"}
+	}
+	return rows
+}
+
+func TestUpsertBatchReportsEachChunk(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	session := &Session{DB: db}
+	session.SQLDialect = dialect.PostgresDialect{}
+	dao := DAO[testUser]{ISession: session, Table: testUserTable()}
+
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "user" ("user_first", "user_id") VALUES ($1, $2), ($3, $4) ON CONFLICT`)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "user" ("user_first", "user_id") VALUES ($1, $2), ($3, $4) ON CONFLICT`)).
+		WillReturnError(assert.AnError)
+	// A failed chunk doesn't stop the rest
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "user" ("user_first", "user_id") VALUES ($1, $2) ON CONFLICT`)).
+		WithArgs("name", 5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	result, err := dao.UpsertBatch(UpsertOptions{ChunkSize: 2}, batchRows(5)...)
+
+	assert.ErrorIs(t, err, assert.AnError)
+	var statementErr *StatementError
+	if assert.ErrorAs(t, err, &statementErr) {
+		assert.Equal(t, "user", statementErr.Table)
+		assert.Contains(t, statementErr.Statement, `INSERT INTO "user"`)
+	}
+	assert.ErrorContains(t, err, "rows 2 to 3: upsert failed: user: "+assert.AnError.Error())
+	assert.Equal(t, int64(3), result.RowsAffected)
+	if assert.Len(t, result.Chunks, 3) {
+		assert.Equal(t, ChunkResult{Offset: 0, Rows: 2, RowsAffected: 2}, result.Chunks[0])
+		assert.Equal(t, 2, result.Chunks[1].Offset)
+		assert.Error(t, result.Chunks[1].Err)
+		assert.Equal(t, ChunkResult{Offset: 4, Rows: 1, RowsAffected: 1}, result.Chunks[2])
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpsertBatchAtomic(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	session := &Session{DB: db}
+	session.SQLDialect = dialect.PostgresDialect{}
+	dao := DAO[testUser]{ISession: session, Table: testUserTable()}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO").WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("INSERT INTO").WillReturnError(assert.AnError)
+	mock.ExpectRollback()
+
+	result, err := dao.UpsertBatch(UpsertOptions{ChunkSize: 2, Atomic: true}, batchRows(5)...)
+
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.Len(t, result.Chunks, 2)
+	assert.Equal(t, int64(0), result.RowsAffected)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestChunkSizeFollowsParameterLimit(t *testing.T) {
+	session, _ := newRecordingSession(dialect.PostgresDialect{})
+	dao := DAO[testUser]{ISession: session, Table: testUserTable()}
+	row := map[string]any{"user_id": 1, "user_first": "name", "user_email": "e"}
+
+	upsert := func(options UpsertOptions) func(*DAO[testUser], []map[string]any) (string, []any) {
+		return func(dao *DAO[testUser], rows []map[string]any) (string, []any) {
+			return dao.upsertStatement(options, rows)
+		}
+	}
+
+	assert.Equal(t, 65535/3, dao.chunkSize(row, upsert(UpsertOptions{})))
+	// The guard's arguments come out of the budget once per statement
+	assert.Equal(t, (65535-2)/3, dao.chunkSize(row, upsert(UpsertOptions{Where: Between("user_id", 1, 9)})))
+}
+
+func TestUpsertSplitsLargeCalls(t *testing.T) {
+	session, rec := newRecordingSession(dialect.PostgresDialect{})
+	dao := DAO[testUser]{ISession: session, Table: testUserTable()}
+
+	// Two columns a row: 32767 rows fit in one statement
+	err := dao.Upsert(batchRows(40000)...)
+
+	assert.NoError(t, err)
+	statements := rec.Statements()
+	if assert.Len(t, statements, 4) {
+		assert.Equal(t, "BEGIN", statements[0].SQL)
+		assert.Len(t, statements[1].Args, 65534)
+		assert.Len(t, statements[2].Args, 2*(40000-32767))
+		assert.Equal(t, "COMMIT", statements[3].SQL)
+	}
+}
+
+func TestUpsertIsAllOrNothing(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	session := &Session{DB: db}
+	session.SQLDialect = dialect.PostgresDialect{}
+	dao := DAO[testUser]{ISession: session, Table: testUserTable()}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO").WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("INSERT INTO").WillReturnError(assert.AnError)
+	mock.ExpectRollback()
+
+	err = dao.UpsertWith(UpsertOptions{ChunkSize: 2}, batchRows(5)...)
+
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
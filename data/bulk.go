@@ -0,0 +1,192 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"iter"
+	"sort"
+)
+
+// BulkLoad streams rows into the table and returns how many were loaded. Every row must set the
+// same columns as the first.
+//
+// On PostgreSQL the rows go through COPY FROM STDIN, which is far faster than INSERT for large
+// loads. Other dialects fall back to multi-row INSERTs, chunked as UpsertBatch chunks them. Either
+// way the load runs in one transaction, so a failure loads nothing.
+//
+// Rows are pulled from the sequence as they are sent, so a load can be fed straight from a file
+// without holding it all in memory. For a slice, pass slices.Values(rows).
+func (dao *DAO[T]) BulkLoad(rows iter.Seq[map[string]any]) (int64, error) {
+	return dao.bulkLoad(func(yield func(map[string]any, error) bool) {
+		for row := range rows {
+			if !yield(row, nil) {
+				return
+			}
+		}
+	})
+}
+
+// BulkLoadStructs streams items into the table as BulkLoad does, deriving each row from T's fields.
+//
+// Every row sets the same columns: each field not tagged readonly, including zero omitempty fields.
+// A serial primary key is left to the database if the first item leaves it zero, and must then be
+// zero in every item; otherwise every item must set it.
+func (dao *DAO[T]) BulkLoadStructs(items iter.Seq[T]) (int64, error) {
+	return dao.bulkLoad(func(yield func(map[string]any, error) bool) {
+		var rule serialKeyRule
+		for item := range items {
+			if !yield(dao.bulkRowFromStruct(item, &rule)) {
+				return
+			}
+		}
+	})
+}
+
+func (dao *DAO[T]) bulkLoad(rows iter.Seq2[map[string]any, error]) (int64, error) {
+	var loaded int64
+	err := inTransaction(dao.ISession, func(tx ISession) error {
+		txDAO := *dao
+		txDAO.ISession = tx
+
+		var loader rowLoader
+		for row, err := range rows {
+			if err != nil {
+				return fmt.Errorf("bulk load into %s, row %d: %w", dao.Table.Name, loaded, err)
+			}
+			if loader == nil {
+				loader, err = txDAO.newLoader(row)
+				if err != nil {
+					return err
+				}
+			}
+			if err := loader.load(row); err != nil {
+				return fmt.Errorf("bulk load into %s, row %d: %w", dao.Table.Name, loaded, err)
+			}
+			loaded++
+		}
+		if loader == nil {
+			return nil // Nothing to load
+		}
+		if err := loader.flush(); err != nil {
+			return fmt.Errorf("bulk load into %s: %w", dao.Table.Name, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return loaded, nil
+}
+
+// rowLoader sends rows to the database for a bulk load. A statement that fails is reported as a
+// *StatementError.
+type rowLoader interface {
+	load(row map[string]any) error
+	flush() error
+}
+
+// newLoader picks the loader for the session's dialect, taking the columns to load from first
+func (dao *DAO[T]) newLoader(first map[string]any) (rowLoader, error) {
+	columns := make([]string, 0, len(first))
+	for column := range first {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	if copyIn := dao.ISession.Dialect().CopyIn(dao.Table.Name, columns); copyIn != "" {
+		stmt, err := dao.ISession.Prepare(copyIn)
+		if err != nil {
+			return nil, fmt.Errorf("starting COPY into %s: %w", dao.Table.Name, dao.statementError(copyIn, err))
+		}
+		return &copyLoader{
+			ctx:     sessionContext(dao.ISession),
+			columns: columns,
+			stmt:    stmt,
+			fail: func(err error) error {
+				return dao.statementError(copyIn, translateError(dao.ISession, err))
+			},
+		}, nil
+	}
+
+	return &insertLoader[T]{
+		dao:       dao,
+		columns:   columns,
+		chunkSize: dao.chunkSize(first, (*DAO[T]).insertStatement),
+	}, nil
+}
+
+// rowValues returns the row's values in column order, checking it sets exactly those columns
+func rowValues(row map[string]any, columns []string) ([]any, error) {
+	if len(row) != len(columns) {
+		return nil, fmt.Errorf("row sets %d columns, the first row set %d", len(row), len(columns))
+	}
+	values := make([]any, len(columns))
+	for i, column := range columns {
+		value, ok := row[column]
+		if !ok {
+			return nil, fmt.Errorf("row doesn't set %s, which the first row did", column)
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+// copyLoader streams rows through a prepared COPY statement
+type copyLoader struct {
+	ctx     context.Context
+	columns []string
+	stmt    *sql.Stmt
+	fail    func(err error) error // Reports the COPY failing
+}
+
+func (l *copyLoader) load(row map[string]any) error {
+	values, err := rowValues(row, l.columns)
+	if err != nil {
+		return err
+	}
+	if _, err := l.stmt.ExecContext(l.ctx, values...); err != nil {
+		return l.fail(err)
+	}
+	return nil
+}
+
+func (l *copyLoader) flush() error {
+	defer l.stmt.Close()
+	// Ends the COPY; the server reports any bad row here
+	if _, err := l.stmt.ExecContext(l.ctx); err != nil {
+		return l.fail(err)
+	}
+	return nil
+}
+
+// insertLoader gathers rows into multi-row INSERTs
+type insertLoader[T any] struct {
+	dao       *DAO[T]
+	columns   []string
+	chunkSize int
+	pending   []map[string]any
+}
+
+func (l *insertLoader[T]) load(row map[string]any) error {
+	if _, err := rowValues(row, l.columns); err != nil {
+		return err
+	}
+	l.pending = append(l.pending, row)
+	if len(l.pending) < l.chunkSize {
+		return nil
+	}
+	return l.flush()
+}
+
+func (l *insertLoader[T]) flush() error {
+	if len(l.pending) == 0 {
+		return nil
+	}
+	query, args := l.dao.insertStatement(l.pending)
+	l.pending = l.pending[:0]
+	if _, err := l.dao.ISession.Exec(query, args...); err != nil {
+		return l.dao.statementError(query, err)
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package data
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"gormless/data/dialect"
+	"regexp"
+	"slices"
+	"testing"
+)
+
+func TestBulkLoadCopiesOnPostgres(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	session := &Session{DB: db}
+	session.SQLDialect = dialect.PostgresDialect{}
+	dao := DAO[testUser]{ISession: session, Table: testUserTable()}
+
+	mock.ExpectBegin()
+	copyIn := mock.ExpectPrepare(regexp.QuoteMeta(`COPY "user" ("user_email", "user_first") FROM STDIN`))
+	copyIn.ExpectExec().WithArgs("john@x", "John").WillReturnResult(sqlmock.NewResult(0, 0))
+	copyIn.ExpectExec().WithArgs("jane@x", "Jane").WillReturnResult(sqlmock.NewResult(0, 0))
+	copyIn.ExpectExec().WithoutArgs().WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	loaded, err := dao.BulkLoadStructs(slices.Values([]testUser{
+		{FirstName: "John", Email: "john@x"},
+		{FirstName: "Jane", Email: "jane@x"},
+	}))
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), loaded)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBulkLoadInsertsOnMySQL(t *testing.T) {
+	session, rec := newRecordingSession(dialect.MySQLDialect{})
+	dao := DAO[testUser]{ISession: session, Table: testUserTable()}
+
+	loaded, err := dao.BulkLoad(slices.Values([]map[string]any{
+		{"user_first": "John", "user_email": "john@x"},
+		{"user_first": "Jane", "user_email": "jane@x"},
+	}))
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), loaded)
+	assert.Equal(t, []Statement{
+		{SQL: "BEGIN"},
+		{SQL: "INSERT INTO `user` (`user_email`, `user_first`) VALUES (?, ?), (?, ?)", Args: []any{"john@x", "John", "jane@x", "Jane"}},
+		{SQL: "COMMIT"},
+	}, rec.Statements())
+}
+
+func TestBulkLoadRowsMustMatch(t *testing.T) {
+	session, rec := newRecordingSession(dialect.MySQLDialect{})
+	dao := DAO[testUser]{ISession: session, Table: testUserTable()}
+
+	loaded, err := dao.BulkLoad(slices.Values([]map[string]any{
+		{"user_first": "John", "user_email": "john@x"},
+		{"user_first": "Jane", "user_id": 2},
+	}))
+
+	assert.EqualError(t, err, "bulk load into user, row 1: row doesn't set user_email, which the first row did")
+	assert.Equal(t, int64(0), loaded)
+	assert.Equal(t, []Statement{{SQL: "BEGIN"}, {SQL: "ROLLBACK"}}, rec.Statements())
+}
+
+func TestBulkLoadStructsSetTheSameColumns(t *testing.T) {
+	session, rec := newRecordingSession(dialect.MySQLDialect{})
+	dao := DAO[testWriteUser]{ISession: session, Table: testUserTable()}
+
+	// Only some rows set the omitempty email
+	loaded, err := dao.BulkLoadStructs(slices.Values([]testWriteUser{
+		{FirstName: "John", Email: "john@x"},
+		{FirstName: "Jane"},
+	}))
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), loaded)
+	assert.Equal(t, []Statement{
+		{SQL: "BEGIN"},
+		{SQL: "INSERT INTO `user` (`user_email`, `user_first`) VALUES (?, ?), (?, ?)", Args: []any{"john@x", "John", "", "Jane"}},
+		{SQL: "COMMIT"},
+	}, rec.Statements())
+
+	// The first row decides whether serial keys are generated for the whole load
+	_, err = dao.BulkLoadStructs(slices.Values([]testWriteUser{{FirstName: "John"}, {ID: 7, FirstName: "Jane"}}))
+	assert.EqualError(t, err, "bulk load into user, row 1: row sets user_id, which the first row left to the database")
+	_, err = dao.BulkLoadStructs(slices.Values([]testWriteUser{{ID: 7, FirstName: "John"}, {FirstName: "Jane"}}))
+	assert.EqualError(t, err, "bulk load into user, row 1: row leaves user_id zero, which the first row set")
+}
+
+func TestBulkLoadReturnsStatementErrors(t *testing.T) {
+	copyIn := `COPY "user" ("user_email", "user_first") FROM STDIN`
+	insert := "INSERT INTO `user` (`user_email`, `user_first`) VALUES (?, ?)"
+	tests := []struct {
+		name      string
+		dialect   dialect.Dialect
+		expect    func(mock sqlmock.Sqlmock)
+		statement string
+	}{
+		{
+			name:    "starting the COPY",
+			dialect: dialect.PostgresDialect{},
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectPrepare(regexp.QuoteMeta(copyIn)).WillReturnError(assert.AnError)
+			},
+			statement: copyIn,
+		},
+		{
+			name:    "sending a row",
+			dialect: dialect.PostgresDialect{},
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectPrepare(regexp.QuoteMeta(copyIn)).ExpectExec().WillReturnError(assert.AnError)
+			},
+			statement: copyIn,
+		},
+		{
+			name:    "ending the COPY",
+			dialect: dialect.PostgresDialect{},
+			expect: func(mock sqlmock.Sqlmock) {
+				stmt := mock.ExpectPrepare(regexp.QuoteMeta(copyIn))
+				stmt.ExpectExec().WithArgs("john@x", "John").WillReturnResult(sqlmock.NewResult(0, 0))
+				stmt.ExpectExec().WithoutArgs().WillReturnError(assert.AnError)
+			},
+			statement: copyIn,
+		},
+		{
+			name:    "inserting",
+			dialect: dialect.MySQLDialect{},
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta(insert)).WillReturnError(assert.AnError)
+			},
+			statement: insert,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("Error creating mock database: %v", err)
+			}
+			defer db.Close()
+
+			session := &Session{DB: db}
+			session.SQLDialect = tt.dialect
+			dao := DAO[testUser]{ISession: session, Table: testUserTable()}
+
+			mock.ExpectBegin()
+			tt.expect(mock)
+			mock.ExpectRollback()
+
+			_, err = dao.BulkLoad(slices.Values([]map[string]any{{"user_first": "John", "user_email": "john@x"}}))
+
+			var statementErr *StatementError
+			if assert.ErrorAs(t, err, &statementErr) {
+				assert.Equal(t, "user", statementErr.Table)
+				assert.Equal(t, tt.statement, statementErr.Statement)
+			}
+			assert.ErrorIs(t, err, assert.AnError)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -1,8 +1,10 @@
 package data
 
 import (
+	"fmt"
 	"gopkg.in/yaml.v3"
 	"os"
+	"time"
 )
 
 // Database holds database configuration
@@ -13,6 +15,44 @@ type Database struct {
 	Password string `yaml:"password"`
 	DBName   string `yaml:"dbname"`
 	SSLMode  string `yaml:"sslmode"`
+	Pool     Pool   `yaml:",inline"` // Connection pool settings, alongside the connection details
+}
+
+// Pool sizes a session's connection pool and sets the limits applied to each of its connections.
+// Zero values leave the database/sql or server defaults in place.
+//
+// E.g., in db_config.yml,
+//
+//	database:
+//	  max_connections: 20
+//	  idle_timeout: 10         # Seconds
+//	  statement_timeout: 2.5s  # Or a duration
+type Pool struct {
+	MaxConnections     int      `yaml:"max_connections"`      // Open connections at most, in use or idle
+	MaxIdleConnections int      `yaml:"max_idle_connections"` // Idle connections kept for reuse; database/sql keeps 2
+	IdleTimeout        Duration `yaml:"idle_timeout"`         // Close connections left idle this long
+	MaxLifetime        Duration `yaml:"max_lifetime"`         // Close connections this old, e.g. to follow a failover
+	ConnectionTimeout  Duration `yaml:"connection_timeout"`   // Give up opening a connection after this long
+	StatementTimeout   Duration `yaml:"statement_timeout"`    // Cancel statements that run longer than this, migrations' DDL included
+	LockTimeout        Duration `yaml:"lock_timeout"`         // Fail statements that wait longer than this for a lock
+}
+
+// Duration is a time.Duration read from YAML as a number of seconds or a duration string, e.g. "500ms"
+type Duration time.Duration
+
+func (d *Duration) UnmarshalYAML(value *yaml.Node) error {
+	var seconds float64
+	if value.Decode(&seconds) == nil {
+		*d = Duration(seconds * float64(time.Second))
+		return nil
+	}
+
+	parsed, err := time.ParseDuration(value.Value)
+	if err != nil {
+		return fmt.Errorf("line %d: %q is neither a number of seconds nor a duration", value.Line, value.Value)
+	}
+	*d = Duration(parsed)
+	return nil
 }
 
 // Config holds all configuration
@@ -1,9 +1,13 @@
 package data
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	"gormless/example_app/user"
+	"gormless/data/dialect"
+	"iter"
+	"reflect"
+	"sort"
 	"strings"
 )
 
@@ -28,29 +32,35 @@ type IDAO interface {
 //
 // The DAO is designed to be used with the data package. It is not intended to be used directly.
 //
+// Typed methods such as FindOne and FindMany scan rows into T by matching struct fields to column
+// names. A field's column is given by its db tag, or is the snake_case form of the field name when
+// it has no tag; a tag of "-" skips the field. Use sql.Null* or pointer fields for nullable columns.
+// UpsertStruct and InsertStruct write T back the same way.
+//
 // E.g.,
-// 	type User struct {
-// 		ID        int
-// 		FirstName string
-// 		LastName  string
-// 		Email     string
-// 	}
-//
-// 	type UserDAO struct {
-// 		data.DAO[User]
-// 	}
-//
-// func (dao *UserDAO) GetDAO(session data.ISession) data.DAO[User] {
-// 	dao.ISession = session
-// 	dao.Table = tables.UserTable()()
-// 	return dao
-// }
-//
-// 	func (dao *UserDAO) Get(id int) (*User, error) {
-// 		row, err := dao.GetOne("id", id)
-// 		if err != nil {
-// 			return nil, err
-// }
+//
+//	type User struct {
+//		ID        int    `db:"user_id"`
+//		FirstName string `db:"user_first"`
+//		LastName  string `db:"user_last"`
+//		Email     string `db:"user_email"`
+//		Nickname  *string
+//	}
+//
+//	type UserDAO struct {
+//		data.DAO[User]
+//	}
+//
+//	func NewUserDAO(session data.ISession) *UserDAO {
+//		return &UserDAO{data.DAO[User]{
+//			ISession: session,
+//			Table:    tables.UserTable()(),
+//		}}
+//	}
+//
+//	func (dao *UserDAO) Get(id int) (User, error) {
+//		return dao.FindOne("user_id", id)
+//	}
 
 type DAO[T any] struct {
 	ISession           // Links the DAO to a specific session
@@ -59,7 +69,6 @@ type DAO[T any] struct {
 	Rows     *sql.Rows // Used for GetMany()
 	Columns  []Column  // Used for Upsert()
 	Values   []string  // Used for Upsert()
-	id       string    // Used for Delete()
 }
 
 type DAOFactory struct {
@@ -71,26 +80,157 @@ func NewDAOFactory(session ISession) *DAOFactory {
 	return &DAOFactory{session: session}
 }
 
-// Upsert handles inserting or updating one or more rows
+// WithContext returns a copy of the DAO whose statements all run under ctx, so cancelling ctx or
+// passing its deadline stops them. A DAO embedded in a type of its own can be given a context by
+// constructing it with data.WithContext(ctx, session) instead.
+//
+// E.g.,
+//
+//	users, err := dao.WithContext(r.Context()).FindMany("user_first", "J%", true)
+func (dao *DAO[T]) WithContext(ctx context.Context) *DAO[T] {
+	bound := *dao
+	bound.ISession = WithContext(ctx, dao.ISession)
+	return &bound
+}
+
+// Upsert handles inserting or updating one or more rows. A row whose primary key already exists has
+// its other columns updated; the dialect renders the clause, e.g. ON CONFLICT on PostgreSQL and
+// ON DUPLICATE KEY UPDATE on MySQL.
 func (dao *DAO[T]) Upsert(rows ...map[string]any) error {
+	return dao.UpsertWith(UpsertOptions{}, rows...)
+}
+
+// UpsertOptions choose what an upsert conflicts on and what it does about it
+type UpsertOptions struct {
+	Target     []string // Columns of a unique key to conflict on. Defaults to the primary key.
+	Constraint string   // Name of a unique constraint to conflict on instead of Target
+	DoNothing  bool     // Keep existing rows as they are
+	Update     []string // Columns to overwrite. Defaults to every inserted column outside Target, or outside the primary key given a Constraint.
+	ChunkSize  int      // Rows per statement. Defaults to as many as the dialect's parameter limit allows.
+	Atomic     bool     // UpsertBatch only: write every chunk in one transaction. Upsert and UpsertWith always do.
+
+	// Where guards the update: only existing rows it holds for are updated. Unqualified columns
+	// refer to the existing row; use Inserted for the incoming value.
+	//
+	// E.g., data.Lt("updated_at", data.Inserted("updated_at")) only updates rows older than the
+	// ones being inserted.
+	Where Condition
+}
+
+// UpsertWith inserts rows, resolving conflicts with existing rows as options say. Large calls are
+// split into several statements written in one transaction, so either every row is written or none
+// is; UpsertBatch can instead keep the chunks that succeed.
+//
+// MySQL checks every unique key for conflicts, whatever Target or Constraint name.
+//
+// E.g., to seed rows without touching ones that are already there,
+//
+//	err := dao.UpsertWith(data.UpsertOptions{Target: []string{"role_name"}, DoNothing: true}, roles...)
+func (dao *DAO[T]) UpsertWith(options UpsertOptions, rows ...map[string]any) error {
 	if len(rows) == 0 {
 		return nil // Nothing to do
 	}
 
-	dialect := dao.ISession.Dialect()
+	options.Atomic = true
+	_, err := dao.UpsertBatch(options, rows...)
+	return err
+}
+
+// upsertStatement builds an upsert of rows and returns it with the arguments bound to it
+func (dao *DAO[T]) upsertStatement(options UpsertOptions, rows []map[string]any) (string, []any) {
+	d := dao.ISession.Dialect()
+
+	var builder strings.Builder
+	columns, args := dao.writeInsert(&builder, rows)
+
+	conflict := dialect.Conflict{
+		Columns:    columns,
+		Target:     dao.conflictTarget(options),
+		Constraint: options.Constraint,
+		Update:     options.Update,
+		DoNothing:  options.DoNothing,
+	}
+	if len(conflict.Update) == 0 {
+		// A named constraint's columns aren't known, but the primary key is never overwritten
+		keep := conflict.Target
+		if options.Constraint != "" {
+			keep, _ = dao.primaryKey()
+		}
+		kept := make(map[string]bool, len(keep))
+		for _, column := range keep {
+			kept[column] = true
+		}
+		for _, column := range columns {
+			if !kept[column] {
+				conflict.Update = append(conflict.Update, column)
+			}
+		}
+	}
+	if options.Where != nil {
+		// Continue numbering from the INSERT's placeholders
+		w := &sqlWriter{dialect: d, args: args, table: dao.Table.Name}
+		options.Where.writeSQL(w)
+		conflict.Where = w.builder.String()
+		conflict.WhereArgs = w.args[len(args):]
+		conflict.WhereColumns = w.columns
+	}
+
+	clause, clauseArgs := d.OnConflict(conflict)
+	builder.WriteString(clause)
+	return builder.String(), append(args, clauseArgs...)
+}
+
+// conflictTarget returns the columns an upsert conflicts on: options.Target, or the primary key
+// unless a constraint is named. No primary key means a plain insert.
+func (dao *DAO[T]) conflictTarget(options UpsertOptions) []string {
+	if len(options.Target) > 0 || options.Constraint != "" {
+		return options.Target
+	}
+	keys, _ := dao.primaryKey()
+	return keys
+}
+
+// insert adds one or more rows, failing on any conflict
+func (dao *DAO[T]) insert(rows ...map[string]any) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	// Chunks go in one transaction so a failed insert can simply be retried
+	_, err := dao.writeChunks("insert", rows, 0, true, (*DAO[T]).insertStatement)
+	return err
+}
+
+// insertStatement builds a bulk INSERT of rows and returns it with the arguments bound to it
+func (dao *DAO[T]) insertStatement(rows []map[string]any) (string, []any) {
+	var builder strings.Builder
+	_, args := dao.writeInsert(&builder, rows)
+	return builder.String(), args
+}
+
+// writeInsert writes a bulk INSERT of rows to builder and returns the columns, in the order they
+// were written, and the arguments bound to it
+func (dao *DAO[T]) writeInsert(builder *strings.Builder, rows []map[string]any) ([]string, []any) {
+	d := dao.ISession.Dialect()
 
 	// Get column names from the first row (assuming all rows have the same columns)
 	firstRow := rows[0]
 	columns := make([]string, 0, len(firstRow))
 	for col := range firstRow {
 		// Don't skip ID column - let the database handle it during conflict resolution
-		columns = append(columns, dialect.QuoteIdentifier(col))
+		columns = append(columns, col)
+	}
+	// Sort so the generated statement doesn't depend on map iteration order
+	sort.Strings(columns)
+
+	quoted := make([]string, len(columns))
+	for i, col := range columns {
+		quoted[i] = d.QuoteIdentifier(col)
 	}
 
 	// Build the bulk insert query
-	var builder strings.Builder
-	builder.WriteString(dialect.Sprintd("INSERT INTO %i (", dao.Table.Name))
-	builder.WriteString(strings.Join(columns, ", "))
+	builder.WriteString(d.Sprintd("INSERT INTO %i (", dao.Table.Name))
+	builder.WriteString(strings.Join(quoted, ", "))
 	builder.WriteString(") VALUES ")
 
 	// Add placeholders for each row
@@ -101,57 +241,201 @@ func (dao *DAO[T]) Upsert(rows ...map[string]any) error {
 		placeholders := make([]string, len(columns))
 
 		for j, col := range columns {
-			// Remove any quotes added by QuoteIdentifier
-			plainCol := strings.Trim(col, "\"'`[]")
-			placeholders[j] = dialect.Placeholder(i*len(columns) + j + 1)
-			args = append(args, row[plainCol])
+			placeholders[j] = d.Placeholder(i*len(columns) + j + 1)
+			args = append(args, row[col])
 		}
 
 		placeholderGroups[i] = "(" + strings.Join(placeholders, ", ") + ")"
 	}
 
 	builder.WriteString(strings.Join(placeholderGroups, ", "))
+	return columns, args
+}
 
-	// Add ON CONFLICT clause for PostgreSQL (upsert)
-	// Assuming the first column with PrimaryKey=true is the conflict target
-	var primaryKeyCol string
-	for _, col := range *dao.Table.Columns {
-		if col.PrimaryKey {
-			primaryKeyCol = col.Name
-			break
-		}
+// UpsertStruct inserts or updates items, deriving each row from T's fields as described on DAO.
+//
+// Fields tagged omitempty are left out while they hold their zero value, and fields tagged readonly
+// are never written, e.g. `db:"created_at,readonly"`. A serial primary key left at zero is left out
+// too, so the database assigns it.
+func (dao *DAO[T]) UpsertStruct(items ...T) error {
+	return dao.writeStructs(items, (*DAO[T]).Upsert)
+}
+
+// UpsertStructWith upserts items as UpsertStruct does, resolving conflicts as options say
+func (dao *DAO[T]) UpsertStructWith(options UpsertOptions, items ...T) error {
+	return dao.writeStructs(items, func(dao *DAO[T], rows ...map[string]any) error {
+		return dao.UpsertWith(options, rows...)
+	})
+}
+
+// InsertStruct inserts items the way UpsertStruct does, but fails rather than update existing rows
+func (dao *DAO[T]) InsertStruct(items ...T) error {
+	return dao.writeStructs(items, (*DAO[T]).insert)
+}
+
+// writeStructs converts items to rows and writes them with write
+func (dao *DAO[T]) writeStructs(items []T, write func(dao *DAO[T], rows ...map[string]any) error) error {
+	batches, err := dao.structBatches(items)
+	if err != nil {
+		return err
 	}
+	return dao.eachBatch(batches, func(dao *DAO[T], rows []map[string]any) error {
+		return write(dao, rows...)
+	})
+}
 
-	if primaryKeyCol != "" {
-		builder.WriteString(dialect.Sprintd(" ON CONFLICT (%i) DO UPDATE SET ", primaryKeyCol))
+// structBatches converts items to rows, grouped by the columns they set. Items that leave out
+// different columns, e.g. some with an ID and some without, can't share an INSERT.
+func (dao *DAO[T]) structBatches(items []T) ([][]map[string]any, error) {
+	batches := make(map[string]int)
+	var grouped [][]map[string]any
+	for _, item := range items {
+		row, err := dao.rowFromStruct(item)
+		if err != nil {
+			return nil, err
+		}
+		key := rowKey(row)
+		i, ok := batches[key]
+		if !ok {
+			i = len(grouped)
+			batches[key] = i
+			grouped = append(grouped, nil)
+		}
+		grouped[i] = append(grouped[i], row)
+	}
+	return grouped, nil
+}
 
-		updateClauses := make([]string, 0, len(columns))
-		for _, col := range columns {
-			plainCol := strings.Trim(col, "\"'`[]")
-			if plainCol != primaryKeyCol {
-				updateClauses = append(updateClauses, dialect.Sprintd(
-					"%s = EXCLUDED.%s",
-					col,
-					dialect.QuoteIdentifier(plainCol),
-				))
+// eachBatch calls write for each batch of rows, in one transaction if there's more than one
+func (dao *DAO[T]) eachBatch(batches [][]map[string]any, write func(dao *DAO[T], rows []map[string]any) error) error {
+	if len(batches) == 0 {
+		return nil
+	}
+	if len(batches) == 1 {
+		return write(dao, batches[0])
+	}
+	return inTransaction(dao.ISession, func(tx ISession) error {
+		txDAO := *dao
+		txDAO.ISession = tx
+		for _, rows := range batches {
+			if err := write(&txDAO, rows); err != nil {
+				return err
 			}
 		}
+		return nil
+	})
+}
 
-		builder.WriteString(strings.Join(updateClauses, ", "))
+// rowFromStruct maps the fields of item to a row of column values
+func (dao *DAO[T]) rowFromStruct(item T) (map[string]any, error) {
+	value := reflect.ValueOf(item)
+	if value.Kind() == reflect.Pointer {
+		value = value.Elem()
+	}
+	mapping, err := mappingFor(value.Type())
+	if err != nil {
+		return nil, err
 	}
 
-	// Execute the query
-	query := builder.String()
-	_, err := dao.ISession.Exec(query, args...)
+	serialKeys := dao.serialKeys()
+	row := make(map[string]any, len(mapping.Fields))
+	for _, field := range mapping.Fields {
+		if field.Options["readonly"] {
+			continue
+		}
+		fieldValue := value.FieldByIndex(field.Index)
+		if fieldValue.IsZero() && (field.Options["omitempty"] || serialKeys[field.Column]) {
+			continue
+		}
+		row[field.Column] = fieldValue.Interface()
+	}
+	if len(row) == 0 {
+		return nil, fmt.Errorf("%s has no columns to write", value.Type())
+	}
+	return row, nil
+}
+
+// serialKeyRule is whether a bulk load leaves serial keys to the database, as decided by its first row
+type serialKeyRule struct {
+	decided  bool
+	generate bool
+}
+
+// bulkRowFromStruct maps the fields of item to a row for a bulk load, which must set the same
+// columns in every row: every writable column is set, even to a zero value, and serial keys are
+// left to the database in every row or in none, as rule says.
+func (dao *DAO[T]) bulkRowFromStruct(item T, rule *serialKeyRule) (map[string]any, error) {
+	value := reflect.ValueOf(item)
+	if value.Kind() == reflect.Pointer {
+		value = value.Elem()
+	}
+	mapping, err := mappingFor(value.Type())
 	if err != nil {
-		return fmt.Errorf("upsert failed: %w", err)
+		return nil, err
 	}
 
-	return nil
+	serialKeys := dao.serialKeys()
+	row := make(map[string]any, len(mapping.Fields))
+	for _, field := range mapping.Fields {
+		if field.Options["readonly"] {
+			continue
+		}
+		fieldValue := value.FieldByIndex(field.Index)
+		if serialKeys[field.Column] {
+			zero := fieldValue.IsZero()
+			if !rule.decided {
+				rule.decided, rule.generate = true, zero
+			}
+			if zero && !rule.generate {
+				return nil, fmt.Errorf("row leaves %s zero, which the first row set", field.Column)
+			}
+			if !zero && rule.generate {
+				return nil, fmt.Errorf("row sets %s, which the first row left to the database", field.Column)
+			}
+			if zero {
+				continue
+			}
+		}
+		row[field.Column] = fieldValue.Interface()
+	}
+	if len(row) == 0 {
+		return nil, fmt.Errorf("%s has no columns to write", value.Type())
+	}
+	return row, nil
+}
+
+// serialKeys returns the primary key columns whose values the database generates
+func (dao *DAO[T]) serialKeys() map[string]bool {
+	serialKeys := make(map[string]bool)
+	for _, column := range tableColumns(dao.Table) {
+		if column.PrimaryKey && isSerial(column) {
+			serialKeys[column.Name] = true
+		}
+	}
+	return serialKeys
+}
+
+// rowKey identifies the set of columns in a row
+func rowKey(row map[string]any) string {
+	columns := make([]string, 0, len(row))
+	for column := range row {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+	return strings.Join(columns, ",")
+}
+
+// isSerial reports whether the database generates the column's values
+func isSerial(column Column) bool {
+	if column.Type == nil {
+		return false
+	}
+	columnType := strings.ToUpper(*column.Type)
+	return strings.Contains(columnType, "SERIAL") || strings.Contains(columnType, "AUTO_INCREMENT")
 }
 
 // ToRowMap is a helper method to convert DAO columns with values to a row map
-func (dao *DAO[T]) ToRowMap(map[string]user.Role) map[string]any {
+func (dao *DAO[T]) ToRowMap(map[string]T) map[string]any {
 	rowMap := make(map[string]any)
 
 	for _, col := range *dao.Table.Columns {
@@ -175,7 +459,7 @@ func (dao *DAO[T]) GetOne(columnName string, value any) (*sql.Row, error) {
 		return nil, err
 	}
 	defer stmt.Close()
-	return stmt.QueryRow(value), nil
+	return stmt.QueryRowContext(sessionContext(dao.ISession), value), nil
 }
 
 // GetMany retrieves multiple rows by column match or pattern
@@ -199,14 +483,200 @@ func (dao *DAO[T]) GetMany(columnName string, value any, patternMatch bool) (*sq
 	}
 	defer stmt.Close()
 
-	return stmt.Query(value)
+	return stmt.QueryContext(sessionContext(dao.ISession), value)
 }
 
-func (dao *DAO[T]) Delete() error {
-	query := dao.ISession.Dialect().Sprintd(
-		"DELETE FROM %i WHERE id = %s",
-		dao.Table.Name,
-		dao.id)
-	_, err := dao.ISession.Exec(query, dao.id)
-	return err
+// FindOne retrieves the first row whose column matches value, scanned into a T.
+// It returns ErrNotFound if nothing matches.
+func (dao *DAO[T]) FindOne(columnName string, value any) (T, error) {
+	return dao.Select().Where(Eq(columnName, value)).One()
+}
+
+// FindMany retrieves every row by column match or pattern, scanned into a []T.
+// Use Select for anything more involved.
+func (dao *DAO[T]) FindMany(columnName string, value any, patternMatch bool) ([]T, error) {
+	condition := Eq(columnName, value)
+	if patternMatch {
+		condition = comparison{columnName, "LIKE", value}
+	}
+	return dao.Select().Where(condition).All()
+}
+
+// Each iterates over the rows meeting every condition, or the whole table given none. See SelectQuery.Each.
+func (dao *DAO[T]) Each(conditions ...Condition) iter.Seq2[T, error] {
+	return dao.Select().Where(conditions...).Each()
+}
+
+// Delete removes the row with item's primary key and returns the number of rows deleted
+func (dao *DAO[T]) Delete(item T) (int64, error) {
+	keys, err := dao.primaryKey()
+	if err != nil {
+		return 0, err
+	}
+	values := make([]any, len(keys))
+	for i, key := range keys {
+		values[i], err = columnValue(item, key)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return dao.DeleteByPK(values...)
+}
+
+// DeleteByPK removes the row with the given primary key, one value per key column in the order
+// they appear in Table.Columns. It returns the number of rows deleted.
+func (dao *DAO[T]) DeleteByPK(key ...any) (int64, error) {
+	condition, err := dao.keyCondition(key)
+	if err != nil {
+		return 0, err
+	}
+	return dao.DeleteWhere(condition)
+}
+
+// DeleteWhere removes the rows meeting every condition and returns the number deleted. At least one
+// condition is required; pass And() to empty the table on purpose.
+func (dao *DAO[T]) DeleteWhere(conditions ...Condition) (int64, error) {
+	w, err := dao.deleteStatement(conditions)
+	if err != nil {
+		return 0, err
+	}
+	return dao.execAffected("delete", w)
+}
+
+// deleteStatement builds a DELETE of the rows meeting every condition
+func (dao *DAO[T]) deleteStatement(conditions []Condition) (*sqlWriter, error) {
+	if len(conditions) == 0 {
+		return nil, fmt.Errorf("refusing to delete from %s without a condition", dao.Table.Name)
+	}
+
+	w := &sqlWriter{dialect: dao.ISession.Dialect()}
+	w.WriteString("DELETE FROM ")
+	w.column(dao.Table.Name)
+	w.WriteString(" WHERE ")
+	And(conditions...).writeSQL(w)
+	return w, nil
+}
+
+// UpdateByPK sets columns of a single row to the given values. The row is identified by the primary
+// key columns in values; every other entry is a column to set. It returns the number of rows updated.
+func (dao *DAO[T]) UpdateByPK(values map[string]any) (int64, error) {
+	w, _, err := dao.updateStatement(values)
+	if err != nil {
+		return 0, err
+	}
+	return dao.execAffected("update", w)
+}
+
+// updateStatement builds an UPDATE of the row whose primary key is in values, and returns it with
+// the condition that picks the row out
+func (dao *DAO[T]) updateStatement(values map[string]any) (*sqlWriter, Condition, error) {
+	keys, err := dao.primaryKey()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	isKey := make(map[string]bool, len(keys))
+	key := make([]any, len(keys))
+	for i, column := range keys {
+		value, ok := values[column]
+		if !ok {
+			return nil, nil, fmt.Errorf("cannot update %s: no value for primary key column %s", dao.Table.Name, column)
+		}
+		key[i] = value
+		isKey[column] = true
+	}
+	condition, err := dao.keyCondition(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	columns := make([]string, 0, len(values))
+	for column := range values {
+		if !isKey[column] {
+			columns = append(columns, column)
+		}
+	}
+	if len(columns) == 0 {
+		return nil, nil, fmt.Errorf("cannot update %s: no columns to set", dao.Table.Name)
+	}
+	// Sort so the generated statement doesn't depend on map iteration order
+	sort.Strings(columns)
+
+	w := &sqlWriter{dialect: dao.ISession.Dialect()}
+	w.WriteString("UPDATE ")
+	w.column(dao.Table.Name)
+	w.WriteString(" SET ")
+	for i, column := range columns {
+		if i > 0 {
+			w.WriteString(", ")
+		}
+		w.column(column)
+		w.WriteString(" = ")
+		w.bind(values[column])
+	}
+	w.WriteString(" WHERE ")
+	condition.writeSQL(w)
+	return w, condition, nil
+}
+
+// UpdateStruct writes item's fields to the row with the same primary key, deriving the columns as
+// UpsertStruct does: readonly fields are left alone and omitempty fields are skipped while zero.
+// It returns the number of rows updated.
+func (dao *DAO[T]) UpdateStruct(item T) (int64, error) {
+	row, err := dao.rowFromStruct(item)
+	if err != nil {
+		return 0, err
+	}
+	return dao.UpdateByPK(row)
+}
+
+// primaryKey returns the names of the primary key columns, in the order they appear in Table.Columns
+func (dao *DAO[T]) primaryKey() ([]string, error) {
+	var keys []string
+	for _, column := range tableColumns(dao.Table) {
+		if column.PrimaryKey {
+			keys = append(keys, column.Name)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("table %s has no primary key", dao.Table.Name)
+	}
+	return keys, nil
+}
+
+// keyCondition matches the row whose primary key columns hold key
+func (dao *DAO[T]) keyCondition(key []any) (Condition, error) {
+	keys, err := dao.primaryKey()
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != len(keys) {
+		return nil, fmt.Errorf("table %s has %d primary key column(s), got %d value(s)",
+			dao.Table.Name, len(keys), len(key))
+	}
+
+	conditions := make([]Condition, len(keys))
+	for i, column := range keys {
+		conditions[i] = Eq(column, key[i])
+	}
+	return And(conditions...), nil
+}
+
+// execAffected runs the statement in w and returns the number of rows it affected
+func (dao *DAO[T]) execAffected(action string, w *sqlWriter) (int64, error) {
+	query := w.builder.String()
+	result, err := dao.ISession.Exec(query, w.args...)
+	if err != nil {
+		return 0, fmt.Errorf("%s failed: %w", action, dao.statementError(query, err))
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: reading rows affected: %w", action, err)
+	}
+	return affected, nil
+}
+
+// statementError reports query failing against the DAO's table
+func (dao *DAO[T]) statementError(query string, err error) error {
+	return &StatementError{Table: dao.Table.Name, Statement: query, Err: err}
 }
@@ -0,0 +1,333 @@
+package data
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"gormless/data/dialect"
+	"regexp"
+	"testing"
+)
+
+type testUser struct {
+	ID        int    `db:"user_id"`
+	FirstName string `db:"user_first"`
+	Email     string `db:"user_email"`
+}
+
+func testUserTable() Table {
+	return Table{
+		Name: "user",
+		Columns: &[]Column{
+			{Name: "user_id", Type: stringPtr(dialect.PsqlSerial), PrimaryKey: true},
+			{Name: "user_first", Type: stringPtr("VARCHAR(32)")},
+			{Name: "user_email", Type: stringPtr("VARCHAR(64)"), Indexed: true},
+		},
+	}
+}
+
+func TestDAOFind(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	session := &Session{DB: db}
+	session.SQLDialect = dialect.PostgresDialect{}
+	dao := DAO[testUser]{ISession: session, Table: testUserTable()}
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM \"user\" WHERE \"user_email\" = $1")).
+		WithArgs("john@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "user_first", "user_email"}).
+			AddRow(1, "John", "john@example.com"))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM \"user\" WHERE \"user_first\" LIKE $1")).
+		WithArgs("J%").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "user_first", "user_email"}).
+			AddRow(1, "John", "john@example.com").
+			AddRow(2, "Jane", "jane@example.com"))
+
+	user, err := dao.FindOne("user_email", "john@example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, testUser{ID: 1, FirstName: "John", Email: "john@example.com"}, user)
+
+	users, err := dao.FindMany("user_first", "J%", true)
+	assert.NoError(t, err)
+	assert.Len(t, users, 2)
+	assert.Equal(t, "Jane", users[1].FirstName)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+type testWriteUser struct {
+	ID        int    `db:"user_id"`
+	FirstName string `db:"user_first"`
+	Email     string `db:"user_email,omitempty"`
+	CreatedAt string `db:"created_at,readonly"`
+}
+
+func TestDAOUpsertStruct(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	session := &Session{DB: db}
+	session.SQLDialect = dialect.PostgresDialect{}
+	dao := DAO[testWriteUser]{ISession: session, Table: testUserTable()}
+
+	mock.ExpectExec(regexp.QuoteMeta(
+		"INSERT INTO \"user\" (\"user_email\", \"user_first\", \"user_id\") VALUES ($1, $2, $3) "+
+			"ON CONFLICT (\"user_id\") DO UPDATE SET \"user_email\" = EXCLUDED.\"user_email\", \"user_first\" = EXCLUDED.\"user_first\"")).
+		WithArgs("john@example.com", "John", 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = dao.UpsertStruct(testWriteUser{ID: 7, FirstName: "John", Email: "john@example.com", CreatedAt: "now"})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDAOInsertStructSkipsGeneratedColumns(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	session := &Session{DB: db}
+	session.SQLDialect = dialect.PostgresDialect{}
+	dao := DAO[testWriteUser]{ISession: session, Table: testUserTable()}
+
+	// Rows leaving out different columns go in separate statements, in one transaction
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO \"user\" (\"user_first\") VALUES ($1), ($2)")).
+		WithArgs("John", "Jane").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO \"user\" (\"user_email\", \"user_first\") VALUES ($1, $2)")).
+		WithArgs("joe@example.com", "Joe").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err = dao.InsertStruct(
+		testWriteUser{FirstName: "John"},
+		testWriteUser{FirstName: "Jane"},
+		testWriteUser{FirstName: "Joe", Email: "joe@example.com"},
+	)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDAODeleteAndUpdate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	session := &Session{DB: db}
+	session.SQLDialect = dialect.PostgresDialect{}
+	dao := DAO[testWriteUser]{ISession: session, Table: testUserTable()}
+
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "user" WHERE "user_id" = $1`)).
+		WithArgs(7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "user" WHERE "user_id" = $1`)).
+		WithArgs(8).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "user" WHERE ("user_first" = $1 OR "user_email" IS NULL)`)).
+		WithArgs("John").
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "user" SET "user_email" = $1, "user_first" = $2 WHERE "user_id" = $3`)).
+		WithArgs("john@example.com", "John", 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	// readonly and empty omitempty fields are left alone
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "user" SET "user_first" = $1 WHERE "user_id" = $2`)).
+		WithArgs("Jack", 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	affected, err := dao.DeleteByPK(7)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), affected)
+
+	affected, err = dao.Delete(testWriteUser{ID: 8})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), affected)
+
+	affected, err = dao.DeleteWhere(Or(Eq("user_first", "John"), IsNull("user_email")))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), affected)
+
+	affected, err = dao.UpdateByPK(map[string]any{"user_id": 7, "user_first": "John", "user_email": "john@example.com"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), affected)
+
+	affected, err = dao.UpdateStruct(testWriteUser{ID: 7, FirstName: "Jack", CreatedAt: "now"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), affected)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDAODeleteAndUpdateNeedKeys(t *testing.T) {
+	session, rec := newRecordingSession(dialect.PostgresDialect{})
+	dao := DAO[testWriteUser]{ISession: session, Table: testUserTable()}
+
+	_, err := dao.DeleteWhere()
+	assert.EqualError(t, err, "refusing to delete from user without a condition")
+
+	_, err = dao.DeleteByPK(1, 2)
+	assert.EqualError(t, err, "table user has 1 primary key column(s), got 2 value(s)")
+
+	_, err = dao.UpdateByPK(map[string]any{"user_first": "John"})
+	assert.EqualError(t, err, "cannot update user: no value for primary key column user_id")
+
+	_, err = dao.UpdateByPK(map[string]any{"user_id": 1})
+	assert.EqualError(t, err, "cannot update user: no columns to set")
+
+	// A zero serial key is left out of the row, so there's nothing to identify it by
+	_, err = dao.UpdateStruct(testWriteUser{FirstName: "John"})
+	assert.Error(t, err)
+
+	keyless := DAO[testWriteUser]{ISession: session, Table: Table{Name: "log"}}
+	_, err = keyless.DeleteByPK(1)
+	assert.EqualError(t, err, "table log has no primary key")
+
+	assert.Empty(t, rec.Statements())
+}
+
+func TestDAOUpsertMySQL(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	session := &Session{DB: db}
+	session.SQLDialect = dialect.MySQLDialect{}
+	dao := DAO[testWriteUser]{ISession: session, Table: testUserTable()}
+
+	mock.ExpectExec(regexp.QuoteMeta(
+		"INSERT INTO `user` (`user_first`, `user_id`) VALUES (?, ?), (?, ?) "+
+			"ON DUPLICATE KEY UPDATE `user_first` = VALUES(`user_first`)")).
+		WithArgs("John", 1, "Jane", 2).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	err = dao.Upsert(
+		map[string]any{"user_id": 1, "user_first": "John"},
+		map[string]any{"user_id": 2, "user_first": "Jane"},
+	)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDAOUpsertWith(t *testing.T) {
+	tests := []struct {
+		name     string
+		dialect  dialect.Dialect
+		options  UpsertOptions
+		expected string
+		args     []any
+	}{
+		{
+			name:     "Do nothing on a unique column",
+			dialect:  dialect.PostgresDialect{},
+			options:  UpsertOptions{Target: []string{"user_email"}, DoNothing: true},
+			expected: `INSERT INTO "user" ("user_email", "user_first") VALUES ($1, $2) ON CONFLICT ("user_email") DO NOTHING`,
+			args:     []any{"john@example.com", "John"},
+		},
+		{
+			name:    "Update listed columns when the guard holds",
+			dialect: dialect.PostgresDialect{},
+			options: UpsertOptions{
+				Target: []string{"user_email"},
+				Update: []string{"user_first"},
+				Where:  And(NotEq("user_first", Inserted("user_first")), Eq("user_first", "Jon")),
+			},
+			expected: `INSERT INTO "user" ("user_email", "user_first") VALUES ($1, $2) ON CONFLICT ("user_email") ` +
+				`DO UPDATE SET "user_first" = EXCLUDED."user_first" ` +
+				`WHERE ("user"."user_first" <> EXCLUDED."user_first" AND "user"."user_first" = $3)`,
+			args: []any{"john@example.com", "John", "Jon"},
+		},
+		{
+			name:     "Named constraint",
+			dialect:  dialect.PostgresDialect{},
+			options:  UpsertOptions{Constraint: "uq_user_email"},
+			expected: `INSERT INTO "user" ("user_email", "user_first") VALUES ($1, $2) ON CONFLICT ON CONSTRAINT "uq_user_email" DO UPDATE SET "user_email" = EXCLUDED."user_email", "user_first" = EXCLUDED."user_first"`,
+			args:     []any{"john@example.com", "John"},
+		},
+		{
+			name:    "MySQL guard",
+			dialect: dialect.MySQLDialect{},
+			options: UpsertOptions{
+				Target: []string{"user_email"},
+				Where:  Eq("user_first", "Jon"),
+			},
+			expected: "INSERT INTO `user` (`user_email`, `user_first`) VALUES (?, ?) ON DUPLICATE KEY UPDATE " +
+				"`user_first` = IF(`user`.`user_first` = ?, VALUES(`user_first`), `user_first`)",
+			args: []any{"john@example.com", "John", "Jon"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session, rec := newRecordingSession(tt.dialect)
+			dao := DAO[testUser]{ISession: session, Table: testUserTable()}
+
+			err := dao.UpsertWith(tt.options, map[string]any{"user_email": "john@example.com", "user_first": "John"})
+
+			assert.NoError(t, err)
+			if assert.Len(t, rec.Statements(), 1) {
+				assert.Equal(t, tt.expected, rec.Statements()[0].SQL)
+				assert.Equal(t, tt.args, rec.Statements()[0].Args)
+			}
+		})
+	}
+}
+
+func TestDAOUpsertOnConstraintKeepsPrimaryKey(t *testing.T) {
+	session, rec := newRecordingSession(dialect.PostgresDialect{})
+	dao := DAO[testUser]{ISession: session, Table: testUserTable()}
+
+	err := dao.UpsertWith(UpsertOptions{Constraint: "uq_user_email"},
+		map[string]any{"user_id": 7, "user_email": "john@example.com", "user_first": "John"})
+
+	assert.NoError(t, err)
+	if assert.Len(t, rec.Statements(), 1) {
+		assert.Equal(t,
+			`INSERT INTO "user" ("user_email", "user_first", "user_id") VALUES ($1, $2, $3) `+
+				`ON CONFLICT ON CONSTRAINT "uq_user_email" DO UPDATE SET "user_email" = EXCLUDED."user_email", "user_first" = EXCLUDED."user_first"`,
+			rec.Statements()[0].SQL)
+	}
+}
+
+func TestDAOErrorsAreTyped(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	session := &Session{DB: db}
+	session.SQLDialect = dialect.PostgresDialect{}
+	dao := DAO[testWriteUser]{ISession: session, Table: testUserTable()}
+
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO \"user\"")).
+		WillReturnError(&pq.Error{Code: "23505", Table: "user", Constraint: "uq_user_on_user_email",
+			Detail: "Key (user_email)=(john@example.com) already exists."})
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM \"user\" WHERE \"user_id\" = $1")).
+		WithArgs(9).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "user_first", "user_email"}))
+
+	err = dao.InsertStruct(testWriteUser{FirstName: "John", Email: "john@example.com"})
+	assert.ErrorIs(t, err, ErrUniqueViolation)
+	assert.ErrorContains(t, err, "insert failed")
+	var dbErr *DatabaseError
+	if assert.ErrorAs(t, err, &dbErr) {
+		assert.Equal(t, "uq_user_on_user_email", dbErr.Constraint)
+		assert.Equal(t, "user_email", dbErr.Column)
+	}
+
+	_, err = dao.FindOne("user_id", 9)
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
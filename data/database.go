@@ -2,35 +2,85 @@ package data
 
 import (
 	"fmt"
-	"log"
+	"time"
 )
 
-func InitDatabaseVersion(session ISession) error {
-	dbVersionType := session.Dialect().Char(32)
+// VersionTable returns the definition of the table that records applied migrations
+func VersionTable(session ISession) Table {
+	dbVersionType := session.Dialect().Char(maxVersionLength)
 	versionDateType := session.Dialect().Timestamp()
+	checksumType := session.Dialect().Char(checksumLength)
 
-	err := session.Ping()
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	dbVersionTable := Table{
-		Name: "version",
+	return Table{
+		Name: versionTable,
 		Columns: &[]Column{
 			{Name: "database_version", PrimaryKey: true, Type: &dbVersionType},
 			{Name: "version_date", Type: &versionDateType},
+			{Name: "checksum", Type: &checksumType},
 		},
 	}
+}
 
-	err = CreateTable(session, dbVersionTable)
+// InitDatabaseVersion creates the version table if it doesn't exist, adding the checksum column
+// to one created by an older release
+func InitDatabaseVersion(session ISession) error {
+	err := session.Ping()
 	if err != nil {
-		return fmt.Errorf("failed to create database_version table: %v", err)
+		return fmt.Errorf("failed to reach the database: %w", err)
 	}
 
-	return err
+	table := VersionTable(session)
+	err = CreateTable(session, table)
+	if err != nil {
+		return fmt.Errorf("failed to create database_version table: %w", err)
+	}
+
+	// Version tables created before checksums were recorded need the column added
+	columns, err := introspectColumns(session, table.Name)
+	if err != nil {
+		return fmt.Errorf("failed to read database_version table: %w", err)
+	}
+	checksum := (*table.Columns)[2]
+	for _, column := range columns {
+		if column.Name == checksum.Name {
+			return nil
+		}
+	}
+	err = AddColumn(table, checksum)(table, session)
+	if err != nil {
+		return fmt.Errorf("failed to add checksum to database_version table: %w", err)
+	}
+
+	return nil
 }
 
-func UpsertDbVersion() error {
+// UpsertDbVersion records a version as applied, stamping it with the current time and the
+// checksum of the migration's SQL. An empty checksum is stored as NULL. A failed write is reported
+// as a *StatementError, as DeleteDbVersion's is.
+func UpsertDbVersion(session ISession, version string, checksum string) error {
+	dao := DAO[any]{
+		ISession: session,
+		Table:    VersionTable(session),
+	}
 
-	return fmt.Errorf("Not implemented")
+	row := map[string]any{
+		"database_version": version,
+		"version_date":     time.Now(),
+		"checksum":         nil,
+	}
+	if checksum != "" {
+		row["checksum"] = checksum
+	}
+	return dao.Upsert(row)
+}
+
+// DeleteDbVersion removes a version from the version table, marking it as no longer applied
+func DeleteDbVersion(session ISession, version string) error {
+	dao := DAO[any]{
+		ISession: session,
+		Table:    VersionTable(session),
+	}
+
+	_, err := dao.DeleteByPK(version)
+	return err
 }
@@ -1,6 +1,7 @@
 package data
 
 import (
+	"errors"
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/stretchr/testify/assert"
 	"gormless/data/dialect"
@@ -22,10 +23,12 @@ func TestInitDatabaseVersion(t *testing.T) {
 	// Set expectations for the database operations
 	mock.ExpectPing()
 
-	expectedSQL := regexp.QuoteMeta("CREATE TABLE IF NOT EXISTS \"version\" (\"database_version\" CHAR(32) PRIMARY KEY, \"version_date\" TIMESTAMP);")
+	expectedSQL := regexp.QuoteMeta("CREATE TABLE IF NOT EXISTS \"version\" (\"database_version\" CHAR(32) PRIMARY KEY, \"version_date\" TIMESTAMP, \"checksum\" CHAR(64));")
 	mock.ExpectPrepare(expectedSQL).
 		ExpectExec().
 		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta(session.SQLDialect.ColumnsQuery())).WithArgs("version").
+		WillReturnRows(versionColumns("database_version", "version_date", "checksum"))
 
 	// Call the function being tested
 	err = InitDatabaseVersion(session)
@@ -40,6 +43,63 @@ func TestInitDatabaseVersion(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestInitDatabaseVersionAddsChecksum(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	session := &Session{DB: db}
+	session.SQLDialect = dialect.PostgresDialect{}
+
+	// The version table predates checksums
+	mock.ExpectPrepare("CREATE TABLE IF NOT EXISTS").
+		ExpectExec().
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta(session.SQLDialect.ColumnsQuery())).WithArgs("version").
+		WillReturnRows(versionColumns("database_version", "version_date"))
+	mock.ExpectExec(regexp.QuoteMeta("ALTER TABLE \"version\" ADD COLUMN \"checksum\" CHAR(64)")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = InitDatabaseVersion(session)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInitDatabaseVersionOnlyAddsMissingChecksum(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	session := &Session{DB: db}
+	session.SQLDialect = dialect.PostgresDialect{}
+
+	// Failing to read the table is reported, not taken to mean the column is missing
+	mock.ExpectPrepare("CREATE TABLE IF NOT EXISTS").
+		ExpectExec().
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta(session.SQLDialect.ColumnsQuery())).WithArgs("version").
+		WillReturnError(errors.New("permission denied for table pg_attribute"))
+
+	err = InitDatabaseVersion(session)
+
+	assert.ErrorContains(t, err, "permission denied")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// versionColumns returns the rows the dialect's columns query gives for the named columns
+func versionColumns(names ...string) *sqlmock.Rows {
+	rows := sqlmock.NewRows([]string{"name", "type", "auto_increment"})
+	for _, name := range names {
+		rows.AddRow(name, "character(32)", false)
+	}
+	return rows
+}
+
 // Integration test - requires a real database
 // This test is disabled by default (prefix with _ to enable)
 func _TestGetDbSessionIntegration(t *testing.T) {
@@ -58,7 +118,7 @@ func _TestGetDbSessionIntegration(t *testing.T) {
 
 	// Run the test in a goroutine
 	go func() {
-		session, err := GetDbSession(dsn, "postgres")
+		session, err := GetDbSession(dsn, "postgres", Pool{})
 		if err != nil {
 			t.Errorf("Failed to get DB session: %v", err)
 			done <- false
@@ -87,3 +147,55 @@ func _TestGetDbSessionIntegration(t *testing.T) {
 		}
 	}
 }
+
+func TestInitDatabaseVersionReturnsErrors(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	session := &Session{DB: db}
+	session.SQLDialect = dialect.PostgresDialect{}
+
+	mock.ExpectPing().WillReturnError(errors.New("connection refused"))
+	mock.ExpectPing()
+	mock.ExpectPrepare("CREATE TABLE IF NOT EXISTS").
+		ExpectExec().
+		WillReturnError(errors.New("permission denied for schema public"))
+
+	err = InitDatabaseVersion(session)
+	assert.ErrorContains(t, err, "connection refused")
+
+	err = InitDatabaseVersion(session)
+	var statementErr *StatementError
+	assert.ErrorAs(t, err, &statementErr)
+	assert.Equal(t, "version", statementErr.Table)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDbVersionWritesReturnStatementErrors(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	session := &Session{DB: db}
+	session.SQLDialect = dialect.PostgresDialect{}
+
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "version"`)).WillReturnError(assert.AnError)
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "version" WHERE "database_version" = $1`)).
+		WithArgs("0001").
+		WillReturnError(assert.AnError)
+
+	for _, err := range []error{UpsertDbVersion(session, "0001", ""), DeleteDbVersion(session, "0001")} {
+		var statementErr *StatementError
+		if assert.ErrorAs(t, err, &statementErr) {
+			assert.Equal(t, "version", statementErr.Table)
+			assert.NotEmpty(t, statementErr.Statement)
+		}
+		assert.ErrorIs(t, err, assert.AnError)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -1,7 +1,9 @@
 package dialect
 
 import (
+	"database/sql/driver"
 	"strings"
+	"time"
 )
 
 const (
@@ -10,6 +12,7 @@ const (
 )
 
 type Dialect interface {
+	Introspection
 	Sprintd(format string, args ...interface{}) string
 	Fprintd(builder *strings.Builder, format string, args ...interface{}) (int, error)
 	Serial() string
@@ -24,6 +27,22 @@ type Dialect interface {
 	Text() string
 	Placeholder(index int) string
 	QuoteIdentifier(name string) string
+	TransactionalDDL() bool
+	AdvisoryLock(name string) (string, []interface{})
+	AdvisoryUnlock(name string) (string, []interface{})
+	AlterColumnType(table, column, columnType string) string
+	DropIndex(table, index string) string
+	DropForeignKey(table, constraint string) string
+	Limit(limit, offset int) string
+	OnConflict(conflict Conflict) (string, []interface{})
+	Inserted(column string) string
+	Returning() string
+	MaxParameters() int
+	CopyIn(table string, columns []string) string
+	Retryable(err error) bool
+	TranslateError(err error) error
+	TimeoutSettings(statement, lock time.Duration) []string
+	Connector(dsn string) (driver.Connector, error)
 	Real() string
 	DoublePrecision() string
 	Numeric(precision, scale int) string
@@ -63,3 +82,66 @@ type Dialect interface {
 	DateRange() string
 	MacAddr8() string
 }
+
+// Conflict describes what an upsert does when an inserted row collides with an existing one
+type Conflict struct {
+	Columns    []string // Every inserted column
+	Target     []string // Columns of the unique key the rows collide on
+	Constraint string   // Name of the unique constraint the rows collide on, instead of Target
+	Update     []string // Columns to overwrite with the inserted values; none means keep the existing row
+	DoNothing  bool     // Keep the existing row even if Update lists columns
+
+	// Where guards the update: only existing rows it holds for are updated. It is rendered SQL whose
+	// placeholders continue the numbering of the INSERT, with WhereArgs bound to them and WhereColumns
+	// listing the columns it reads.
+	Where        string
+	WhereArgs    []interface{}
+	WhereColumns []string
+}
+
+// formatd rewrites a dialect format string for the fmt package. Every %i verb
+// becomes %s and the argument in the same position is passed through quote;
+// all other verbs and their arguments are left untouched.
+func formatd(quote func(string) string, format string, args ...interface{}) (string, []interface{}) {
+	processedArgs := make([]interface{}, len(args))
+	copy(processedArgs, args)
+
+	var processedFormat strings.Builder
+	argIndex := 0
+	for i := 0; i < len(format); i++ {
+		processedFormat.WriteByte(format[i])
+		if format[i] != '%' {
+			continue
+		}
+
+		// Skip flags, width and precision to find the verb
+		j := i + 1
+		for j < len(format) && strings.IndexByte("+-# 0123456789.", format[j]) >= 0 {
+			j++
+		}
+		if j >= len(format) {
+			break
+		}
+		if format[j] == '%' {
+			processedFormat.WriteByte('%')
+			i = j
+			continue
+		}
+
+		processedFormat.WriteString(format[i+1 : j])
+		if format[j] == 'i' {
+			processedFormat.WriteByte('s')
+			if argIndex < len(args) {
+				if s, ok := args[argIndex].(string); ok {
+					processedArgs[argIndex] = quote(s)
+				}
+			}
+		} else {
+			processedFormat.WriteByte(format[j])
+		}
+		argIndex++
+		i = j
+	}
+
+	return processedFormat.String(), processedArgs
+}
@@ -0,0 +1,54 @@
+package dialect
+
+import (
+	"errors"
+	"regexp"
+)
+
+// Kinds of database error a dialect recognises. A *DatabaseError matches its kind with errors.Is.
+var (
+	ErrUniqueViolation     = errors.New("unique violation")
+	ErrForeignKeyViolation = errors.New("foreign key violation")
+	ErrNotNullViolation    = errors.New("not null violation")
+	ErrCheckViolation      = errors.New("check violation")
+	ErrSerialization       = errors.New("serialization failure") // Includes deadlocks; the transaction can be retried
+)
+
+// DatabaseError is a driver error that a dialect has recognised, with what the database said about
+// its cause. Fields the database didn't report are empty.
+//
+// E.g.,
+//
+//	var dbErr *dialect.DatabaseError
+//	if errors.As(err, &dbErr) && errors.Is(err, dialect.ErrUniqueViolation) {
+//		return fmt.Errorf("%s is already taken", dbErr.Column)
+//	}
+type DatabaseError struct {
+	Kind       error  // One of the Err*Violation errors, or ErrSerialization
+	Constraint string // Name of the violated constraint or unique key
+	Table      string
+	Column     string // First column of the constraint, or the column that was NULL
+	Err        error  // The driver's error
+}
+
+func (e *DatabaseError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns both the kind and the driver's error, so either can be matched with errors.Is or
+// errors.As
+func (e *DatabaseError) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+// keyColumnPattern picks the first column out of "Key (email)=(...)" and "FOREIGN KEY (`role_id`)"
+var keyColumnPattern = regexp.MustCompile("(?:Key|KEY) \\(`?\"?([^`\",)]+)")
+
+// keyColumn returns the first key column named in a database error's message, or ""
+func keyColumn(message string) string {
+	match := keyColumnPattern.FindStringSubmatch(message)
+	if match == nil {
+		return ""
+	}
+	return match[1]
+}
@@ -0,0 +1,22 @@
+package dialect
+
+// Introspection is the part of a Dialect that reads schema back out of a live database.
+//
+// Every query takes the table name as its only bound argument (TablesQuery takes none) and is
+// scoped to the connection's current schema or database. The data package runs the queries and
+// assembles the results; the columns each query must return are listed below.
+type Introspection interface {
+	// TablesQuery returns: table_name
+	TablesQuery() string
+	// ColumnsQuery returns, in column order: column_name, column_type, auto_increment
+	ColumnsQuery() string
+	// PrimaryKeyQuery returns, in key order: column_name
+	PrimaryKeyQuery() string
+	// IndexesQuery returns every non-primary index, ordered by index then position: index_name, column_name, is_unique
+	IndexesQuery() string
+	// ForeignKeysQuery returns: column_name, referenced_table, referenced_column, constraint_name
+	ForeignKeysQuery() string
+	// ColumnType converts a column_type reported by ColumnsQuery into the form the dialect's type
+	// methods produce, so a live column can be compared with a column defined in Go.
+	ColumnType(columnType string, autoIncrement bool) string
+}
@@ -1,8 +1,14 @@
 package dialect
 
 import (
+	"database/sql/driver"
+	"errors"
 	"fmt"
+	"github.com/go-sql-driver/mysql"
+	"math"
+	"regexp"
 	"strings"
+	"time"
 )
 
 const (
@@ -51,66 +57,21 @@ const (
 type MySQLDialect struct{}
 
 func (m MySQLDialect) Sprintd(format string, args ...interface{}) string {
-	// Process the args to quote any identifiers
-	processedArgs := make([]interface{}, len(args))
-	for i, arg := range args {
-		if s, ok := arg.(string); ok && format[strings.Index(format, "%"):strings.Index(format, "%")+2] == "%i" {
-			// If the format specifier is %i, quote the string as an identifier
-			processedArgs[i] = m.QuoteIdentifier(s)
-		} else {
-			processedArgs[i] = arg
-		}
-	}
-
-	// Replace %i with %s in the format string
-	format = strings.ReplaceAll(format, "%i", "%s")
+	// Quote the arguments that line up with a %i verb
+	processedFormat, processedArgs := formatd(m.QuoteIdentifier, format, args...)
 
 	// Use fmt.Sprintf with the processed arguments
-	return fmt.Sprintf(format, processedArgs...)
+	return fmt.Sprintf(processedFormat, processedArgs...)
 }
 
 func (m MySQLDialect) Fprintd(builder *strings.Builder, format string, args ...interface{}) (int, error) {
-	// Process the args to quote any identifiers
-	processedArgs, processedFormat := m.processFormat(format, args...)
+	// Quote the arguments that line up with a %i verb
+	processedFormat, processedArgs := formatd(m.QuoteIdentifier, format, args...)
 
 	// Use fmt.Fprintf with the processed arguments
 	return fmt.Fprintf(builder, processedFormat, processedArgs...)
 }
 
-// Helper method to process format string and arguments
-func (m MySQLDialect) processFormat(format string, args ...interface{}) ([]interface{}, string) {
-	processedArgs := make([]interface{}, len(args))
-
-	// Find all %i format specifiers
-	var specifierIndices []int
-	for i := 0; i < len(format)-1; i++ {
-		if format[i] == '%' && i+1 < len(format) && format[i+1] == 'i' {
-			specifierIndices = append(specifierIndices, i)
-		}
-	}
-
-	// Process the arguments
-	argIndex := 0
-	for i, arg := range args {
-		if argIndex < len(specifierIndices) {
-			// If this argument corresponds to a %i specifier
-			if s, ok := arg.(string); ok {
-				processedArgs[i] = m.QuoteIdentifier(s)
-			} else {
-				processedArgs[i] = arg
-			}
-			argIndex++
-		} else {
-			processedArgs[i] = arg
-		}
-	}
-
-	// Replace %i with %s in the format string
-	processedFormat := strings.ReplaceAll(format, "%i", "%s")
-
-	return processedArgs, processedFormat
-}
-
 // Data type implementations using constants
 func (m MySQLDialect) Serial() string            { return fmt.Sprintf("%s AUTO_INCREMENT", MySqlInt) }
 func (m MySQLDialect) SmallSerial() string       { return fmt.Sprintf("%s AUTO_INCREMENT", MySqlSmallInt) }
@@ -228,5 +189,269 @@ func (m MySQLDialect) Placeholder(index int) string {
 
 // QuoteIdentifier quotes an identifier
 func (m MySQLDialect) QuoteIdentifier(name string) string {
-	return fmt.Sprintf("`%s`", name)
+	return fmt.Sprintf("`%s`", strings.ReplaceAll(name, "`", "``"))
+}
+
+// TransactionalDDL reports false: MySQL implicitly commits before and after every DDL statement
+func (m MySQLDialect) TransactionalDDL() bool {
+	return false
+}
+
+// AdvisoryLock tries to take the named lock without waiting; the query returns 1 if it was taken
+func (m MySQLDialect) AdvisoryLock(name string) (string, []interface{}) {
+	return "SELECT GET_LOCK(?, 0)", []interface{}{name}
+}
+
+// AdvisoryUnlock releases a lock taken with AdvisoryLock
+func (m MySQLDialect) AdvisoryUnlock(name string) (string, []interface{}) {
+	return "SELECT RELEASE_LOCK(?)", []interface{}{name}
+}
+
+func (m MySQLDialect) AlterColumnType(table, column, columnType string) string {
+	return m.Sprintd("ALTER TABLE %i MODIFY COLUMN %i %s", table, column, columnType)
+}
+
+func (m MySQLDialect) DropIndex(table, index string) string {
+	return m.Sprintd("DROP INDEX %i ON %i", index, table)
+}
+
+func (m MySQLDialect) DropForeignKey(table, constraint string) string {
+	return m.Sprintd("ALTER TABLE %i DROP FOREIGN KEY %i", table, constraint)
+}
+
+// Limit renders a LIMIT/OFFSET clause. A negative limit or offset is left out. MySQL has no OFFSET
+// without LIMIT, so an offset on its own is paired with the largest possible limit.
+func (m MySQLDialect) Limit(limit, offset int) string {
+	if limit < 0 && offset < 0 {
+		return ""
+	}
+	if limit < 0 {
+		return fmt.Sprintf("LIMIT 18446744073709551615 OFFSET %d", offset)
+	}
+	if offset < 0 {
+		return fmt.Sprintf("LIMIT %d", limit)
+	}
+	return fmt.Sprintf("LIMIT %d OFFSET %d", limit, offset)
+}
+
+// OnConflict renders an ON DUPLICATE KEY UPDATE clause, along with the arguments bound to it.
+//
+// MySQL checks every unique key rather than a chosen target or constraint, so those only decide
+// whether the clause is added at all. Keeping the existing row sets a column to itself, which leaves
+// the row as it was without INSERT IGNORE swallowing unrelated errors.
+//
+// MySQL has no WHERE on the update, so a guard becomes an IF() around each assignment. Assignments
+// are applied left to right, each seeing the ones before it, so the columns the guard reads are
+// assigned last.
+func (m MySQLDialect) OnConflict(conflict Conflict) (string, []interface{}) {
+	if len(conflict.Target) == 0 && conflict.Constraint == "" && !conflict.DoNothing {
+		return "", nil // As on PostgreSQL, a duplicate fails the insert
+	}
+
+	if conflict.DoNothing || len(conflict.Update) == 0 {
+		keep := conflict.Target
+		if len(keep) == 0 {
+			keep = conflict.Columns
+		}
+		if len(keep) == 0 {
+			return "", nil
+		}
+		return m.Sprintd(" ON DUPLICATE KEY UPDATE %i = %i", keep[0], keep[0]), nil
+	}
+
+	readByGuard := make(map[string]bool, len(conflict.WhereColumns))
+	for _, column := range conflict.WhereColumns {
+		readByGuard[column] = true
+	}
+	ordered := make([]string, 0, len(conflict.Update))
+	for _, column := range conflict.Update {
+		if !readByGuard[column] {
+			ordered = append(ordered, column)
+		}
+	}
+	for _, column := range conflict.Update {
+		if readByGuard[column] {
+			ordered = append(ordered, column)
+		}
+	}
+
+	var args []interface{}
+	updates := make([]string, len(ordered))
+	for i, column := range ordered {
+		if conflict.Where == "" {
+			updates[i] = m.Sprintd("%i = %s", column, m.Inserted(column))
+			continue
+		}
+		updates[i] = m.Sprintd("%i = IF(%s, %s, %i)", column, conflict.Where, m.Inserted(column), column)
+		args = append(args, conflict.WhereArgs...)
+	}
+	return " ON DUPLICATE KEY UPDATE " + strings.Join(updates, ", "), args
+}
+
+// Inserted refers to the value an upsert tried to insert into column. VALUES() still works on 8.0
+// and, unlike the row alias form, on 5.7 as well.
+func (m MySQLDialect) Inserted(column string) string {
+	return m.Sprintd("VALUES(%i)", column)
+}
+
+// Returning is empty: MySQL has no RETURNING, so written rows have to be read back separately
+func (m MySQLDialect) Returning() string {
+	return ""
+}
+
+// MaxParameters is the most placeholders a prepared statement can take. Statements are also bound
+// by the server's max_allowed_packet, which wide rows can hit first.
+func (m MySQLDialect) MaxParameters() int {
+	return 65535
+}
+
+// CopyIn is empty: bulk loads on MySQL fall back to multi-row INSERTs
+func (m MySQLDialect) CopyIn(table string, columns []string) string {
+	return ""
+}
+
+// Retryable reports whether err is a deadlock (error 1213), after which MySQL has rolled the
+// transaction back and it can be run again
+func (m MySQLDialect) Retryable(err error) bool {
+	number, _, ok := mysqlError(err)
+	return ok && number == 1213
+}
+
+var (
+	mysqlDuplicateKey = regexp.MustCompile(`for key '(?:([^.']+)\.)?([^']+)'`)
+	mysqlForeignKey   = regexp.MustCompile("`([^`]+)`, CONSTRAINT `([^`]+)`")
+	mysqlNullColumn   = regexp.MustCompile(`(?:Column|Field) '([^']+)'`)
+	mysqlCheck        = regexp.MustCompile(`constraint '([^']+)'`)
+)
+
+// TranslateError wraps a go-sql-driver/mysql error for a constraint violation or deadlock in a
+// *DatabaseError, reading the constraint and column out of the server's message. Other errors are
+// returned as they are.
+func (m MySQLDialect) TranslateError(err error) error {
+	number, message, ok := mysqlError(err)
+	var dbErr *DatabaseError
+	if !ok || errors.As(err, &dbErr) {
+		return err
+	}
+
+	translated := &DatabaseError{Err: err}
+	switch number {
+	case 1062: // Duplicate entry 'jane@example.com' for key 'user.uq_user_email'
+		translated.Kind = ErrUniqueViolation
+		if match := mysqlDuplicateKey.FindStringSubmatch(message); match != nil {
+			translated.Table, translated.Constraint = match[1], match[2]
+		}
+	case 1451, 1452: // ... a foreign key constraint fails (`db`.`user`, CONSTRAINT `fk` FOREIGN KEY (`user_role`) ...
+		translated.Kind = ErrForeignKeyViolation
+		if match := mysqlForeignKey.FindStringSubmatch(message); match != nil {
+			translated.Table, translated.Constraint = match[1], match[2]
+		}
+		translated.Column = keyColumn(message)
+	case 1048, 1364: // Column 'user_email' cannot be null, or Field 'user_email' doesn't have a default value
+		translated.Kind = ErrNotNullViolation
+		if match := mysqlNullColumn.FindStringSubmatch(message); match != nil {
+			translated.Column = match[1]
+		}
+	case 3819: // Check constraint 'chk_user_age' is violated.
+		translated.Kind = ErrCheckViolation
+		if match := mysqlCheck.FindStringSubmatch(message); match != nil {
+			translated.Constraint = match[1]
+		}
+	case 1213:
+		translated.Kind = ErrSerialization
+	default:
+		return err
+	}
+	return translated
+}
+
+// mysqlError finds a go-sql-driver/mysql *MySQLError in err's chain and returns its server error
+// number and message
+func mysqlError(err error) (uint16, string, bool) {
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return 0, "", false
+	}
+	return mysqlErr.Number, mysqlErr.Message, true
+}
+
+// TimeoutSettings sets max_execution_time, in milliseconds, and innodb_lock_wait_timeout, in whole
+// seconds, for the session. MySQL only applies max_execution_time to SELECTs. A zero timeout is left
+// at the server's setting.
+func (m MySQLDialect) TimeoutSettings(statement, lock time.Duration) []string {
+	var settings []string
+	if statement > 0 {
+		settings = append(settings, fmt.Sprintf("SET SESSION max_execution_time = %d", max(statement.Milliseconds(), 1)))
+	}
+	if lock > 0 {
+		seconds := int64(math.Ceil(lock.Seconds()))
+		settings = append(settings, fmt.Sprintf("SET SESSION innodb_lock_wait_timeout = %d", seconds))
+	}
+	return settings
+}
+
+// Connector opens connections through go-sql-driver/mysql, from a DSN such as
+// "user:password@tcp(localhost:3306)/app?parseTime=true"
+func (m MySQLDialect) Connector(dsn string) (driver.Connector, error) {
+	config, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return mysql.NewConnector(config)
+}
+
+func (m MySQLDialect) TablesQuery() string {
+	return `SELECT TABLE_NAME
+FROM information_schema.TABLES
+WHERE TABLE_SCHEMA = DATABASE() AND TABLE_TYPE = 'BASE TABLE'
+ORDER BY TABLE_NAME`
+}
+
+func (m MySQLDialect) ColumnsQuery() string {
+	return `SELECT COLUMN_NAME, COLUMN_TYPE, EXTRA LIKE '%auto_increment%'
+FROM information_schema.COLUMNS
+WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?
+ORDER BY ORDINAL_POSITION`
+}
+
+func (m MySQLDialect) PrimaryKeyQuery() string {
+	return `SELECT COLUMN_NAME
+FROM information_schema.KEY_COLUMN_USAGE
+WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND CONSTRAINT_NAME = 'PRIMARY'
+ORDER BY ORDINAL_POSITION`
+}
+
+func (m MySQLDialect) IndexesQuery() string {
+	return `SELECT INDEX_NAME, COLUMN_NAME, NON_UNIQUE = 0
+FROM information_schema.STATISTICS
+WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND INDEX_NAME <> 'PRIMARY'
+ORDER BY INDEX_NAME, SEQ_IN_INDEX`
+}
+
+func (m MySQLDialect) ForeignKeysQuery() string {
+	return `SELECT COLUMN_NAME, REFERENCED_TABLE_NAME, REFERENCED_COLUMN_NAME, CONSTRAINT_NAME
+FROM information_schema.KEY_COLUMN_USAGE
+WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND REFERENCED_TABLE_NAME IS NOT NULL
+ORDER BY CONSTRAINT_NAME, ORDINAL_POSITION`
+}
+
+// mysqlDisplayWidth matches the display width MySQL 5.7 reports on integer types, e.g. int(11)
+var mysqlDisplayWidth = regexp.MustCompile(`^(tinyint|smallint|mediumint|int|bigint)\(\d+\)`)
+
+func (m MySQLDialect) ColumnType(columnType string, autoIncrement bool) string {
+	columnType = strings.ToLower(strings.TrimSpace(columnType))
+
+	// BOOLEAN is stored as TINYINT(1); keep that one's width so it reads back as a boolean
+	if columnType == "tinyint(1)" {
+		return MySqlBoolean
+	}
+	columnType = mysqlDisplayWidth.ReplaceAllString(columnType, "$1")
+	// DECIMAL(p,s) is reported without the space the dialect writes
+	columnType = strings.ReplaceAll(columnType, ",", ", ")
+
+	normalized := strings.ToUpper(columnType)
+	if autoIncrement {
+		normalized += " AUTO_INCREMENT"
+	}
+	return normalized
 }
@@ -0,0 +1,181 @@
+package dialect
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestMySQLSprintd(t *testing.T) {
+	d := MySQLDialect{}
+	assert.Equal(t,
+		"SELECT * FROM `user` WHERE `user_id` = ?",
+		d.Sprintd("SELECT * FROM %i WHERE %i = %s", "user", "user_id", d.Placeholder(1)))
+}
+
+func TestMySQLColumnType(t *testing.T) {
+	d := MySQLDialect{}
+	assert.Equal(t, d.Serial(), d.ColumnType("int(11)", true))
+	assert.Equal(t, d.Serial(), d.ColumnType("int", true))
+	assert.Equal(t, "INT UNSIGNED", d.ColumnType("int(10) unsigned", false))
+	assert.Equal(t, d.VarChar(64), d.ColumnType("varchar(64)", false))
+	assert.Equal(t, d.Numeric(19, 4), d.ColumnType("decimal(19,4)", false))
+	assert.Equal(t, d.Boolean(), d.ColumnType("tinyint(1)", false))
+}
+
+func TestMySQLLimit(t *testing.T) {
+	d := MySQLDialect{}
+	assert.Equal(t, "", d.Limit(-1, -1))
+	assert.Equal(t, "LIMIT 10", d.Limit(10, -1))
+	assert.Equal(t, "LIMIT 10 OFFSET 20", d.Limit(10, 20))
+	assert.Equal(t, "LIMIT 18446744073709551615 OFFSET 20", d.Limit(-1, 20))
+	assert.Equal(t, "`odd``name`", d.QuoteIdentifier("odd`name"))
+}
+
+func TestMySQLOnConflict(t *testing.T) {
+	d := MySQLDialect{}
+	tests := []struct {
+		name     string
+		conflict Conflict
+		expected string
+		args     []interface{}
+	}{
+		{
+			name:     "No target",
+			conflict: Conflict{Update: []string{"name"}},
+			expected: "",
+		},
+		{
+			name:     "Update on a key",
+			conflict: Conflict{Target: []string{"id"}, Update: []string{"name", "email"}},
+			expected: " ON DUPLICATE KEY UPDATE `name` = VALUES(`name`), `email` = VALUES(`email`)",
+		},
+		{
+			name:     "Nothing to update",
+			conflict: Conflict{Target: []string{"id"}},
+			expected: " ON DUPLICATE KEY UPDATE `id` = `id`",
+		},
+		{
+			name:     "Do nothing on any key",
+			conflict: Conflict{Columns: []string{"name"}, DoNothing: true, Update: []string{"name"}},
+			expected: " ON DUPLICATE KEY UPDATE `name` = `name`",
+		},
+		{
+			name: "Guarded update assigns the guard's columns last",
+			conflict: Conflict{
+				Target:       []string{"id"},
+				Update:       []string{"updated_at", "name"},
+				Where:        "`t`.`updated_at` < VALUES(`updated_at`) AND `t`.`locked` = ?",
+				WhereArgs:    []interface{}{false},
+				WhereColumns: []string{"updated_at", "locked"},
+			},
+			expected: " ON DUPLICATE KEY UPDATE " +
+				"`name` = IF(`t`.`updated_at` < VALUES(`updated_at`) AND `t`.`locked` = ?, VALUES(`name`), `name`), " +
+				"`updated_at` = IF(`t`.`updated_at` < VALUES(`updated_at`) AND `t`.`locked` = ?, VALUES(`updated_at`), `updated_at`)",
+			args: []interface{}{false, false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clause, args := d.OnConflict(tt.conflict)
+			assert.Equal(t, tt.expected, clause)
+			assert.Equal(t, tt.args, args)
+		})
+	}
+}
+
+func TestMySQLRetryable(t *testing.T) {
+	d := MySQLDialect{}
+	deadlock := &mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"}
+	assert.True(t, d.Retryable(deadlock))
+	assert.True(t, d.Retryable(errors.Join(errors.New("rows 0 to 9"), fmt.Errorf("upsert failed: %w", deadlock))))
+	assert.False(t, d.Retryable(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry"}))
+	assert.False(t, d.Retryable(errors.New("connection refused")))
+}
+
+func TestMySQLTranslateError(t *testing.T) {
+	d := MySQLDialect{}
+	tests := []struct {
+		name     string
+		err      *mysql.MySQLError
+		kind     error
+		expected DatabaseError
+	}{
+		{
+			name:     "unique",
+			err:      &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'jane@example.com' for key 'user.uq_user_on_user_email'"},
+			kind:     ErrUniqueViolation,
+			expected: DatabaseError{Constraint: "uq_user_on_user_email", Table: "user"},
+		},
+		{
+			name:     "unique before 8.0",
+			err:      &mysql.MySQLError{Number: 1062, Message: "Duplicate entry '1' for key 'PRIMARY'"},
+			kind:     ErrUniqueViolation,
+			expected: DatabaseError{Constraint: "PRIMARY"},
+		},
+		{
+			name: "foreign key",
+			err: &mysql.MySQLError{Number: 1452, Message: "Cannot add or update a child row: a foreign key constraint fails " +
+				"(`app`.`user`, CONSTRAINT `fk_user_user_role` FOREIGN KEY (`user_role`) REFERENCES `user_role` (`role_id`))"},
+			kind:     ErrForeignKeyViolation,
+			expected: DatabaseError{Constraint: "fk_user_user_role", Table: "user", Column: "user_role"},
+		},
+		{
+			name:     "not null",
+			err:      &mysql.MySQLError{Number: 1048, Message: "Column 'user_email' cannot be null"},
+			kind:     ErrNotNullViolation,
+			expected: DatabaseError{Column: "user_email"},
+		},
+		{
+			name:     "check",
+			err:      &mysql.MySQLError{Number: 3819, Message: "Check constraint 'chk_user_age' is violated."},
+			kind:     ErrCheckViolation,
+			expected: DatabaseError{Constraint: "chk_user_age"},
+		},
+		{
+			name: "deadlock",
+			err:  &mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"},
+			kind: ErrSerialization,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := d.TranslateError(tt.err)
+			assert.ErrorIs(t, err, tt.kind)
+			assert.ErrorIs(t, err, tt.err)
+
+			var dbErr *DatabaseError
+			if assert.ErrorAs(t, err, &dbErr) {
+				assert.Equal(t, tt.expected.Constraint, dbErr.Constraint)
+				assert.Equal(t, tt.expected.Table, dbErr.Table)
+				assert.Equal(t, tt.expected.Column, dbErr.Column)
+			}
+		})
+	}
+
+	other := &mysql.MySQLError{Number: 1146, Message: "Table 'app.missing' doesn't exist"}
+	assert.Same(t, other, d.TranslateError(other))
+}
+
+func TestMySQLTimeoutSettings(t *testing.T) {
+	d := MySQLDialect{}
+	assert.Equal(t,
+		[]string{"SET SESSION max_execution_time = 5000", "SET SESSION innodb_lock_wait_timeout = 1"},
+		d.TimeoutSettings(5*time.Second, 500*time.Millisecond))
+	assert.Equal(t, []string{"SET SESSION innodb_lock_wait_timeout = 3"}, d.TimeoutSettings(0, 3*time.Second))
+}
+
+func TestMySQLConnector(t *testing.T) {
+	d := MySQLDialect{}
+	connector, err := d.Connector("app:secret@tcp(localhost:3306)/app?parseTime=true")
+	assert.NoError(t, err)
+	assert.IsType(t, &mysql.MySQLDriver{}, connector.Driver())
+
+	_, err = d.Connector("user=app dbname=app")
+	assert.Error(t, err)
+}
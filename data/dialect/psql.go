@@ -1,8 +1,14 @@
 package dialect
 
 import (
+	"database/sql/driver"
+	"errors"
 	"fmt"
+	"github.com/lib/pq"
+	"hash/fnv"
+	"regexp"
 	"strings"
+	"time"
 )
 
 const (
@@ -59,66 +65,21 @@ const (
 type PostgresDialect struct{}
 
 func (p PostgresDialect) Sprintd(format string, args ...interface{}) string {
-	// Process the args to quote any identifiers
-	processedArgs := make([]interface{}, len(args))
-	for i, arg := range args {
-		if s, ok := arg.(string); ok && format[strings.Index(format, "%"):strings.Index(format, "%")+2] == "%i" {
-			// If the format specifier is %i, quote the string as an identifier
-			processedArgs[i] = p.QuoteIdentifier(s)
-		} else {
-			processedArgs[i] = arg
-		}
-	}
-
-	// Replace %i with %s in the format string
-	format = strings.ReplaceAll(format, "%i", "%s")
+	// Quote the arguments that line up with a %i verb
+	processedFormat, processedArgs := formatd(p.QuoteIdentifier, format, args...)
 
 	// Use fmt.Sprintf with the processed arguments
-	return fmt.Sprintf(format, processedArgs...)
+	return fmt.Sprintf(processedFormat, processedArgs...)
 }
 
 func (p PostgresDialect) Fprintd(builder *strings.Builder, format string, args ...interface{}) (int, error) {
-	// Process the args to quote any identifiers
-	processedArgs, processedFormat := p.processFormat(format, args...)
+	// Quote the arguments that line up with a %i verb
+	processedFormat, processedArgs := formatd(p.QuoteIdentifier, format, args...)
 
 	// Use fmt.Fprintf with the processed arguments
 	return fmt.Fprintf(builder, processedFormat, processedArgs...)
 }
 
-// Helper method to process format string and arguments
-func (p PostgresDialect) processFormat(format string, args ...interface{}) ([]interface{}, string) {
-	processedArgs := make([]interface{}, len(args))
-
-	// Find all %i format specifiers
-	var specifierIndices []int
-	for i := 0; i < len(format)-1; i++ {
-		if format[i] == '%' && i+1 < len(format) && format[i+1] == 'i' {
-			specifierIndices = append(specifierIndices, i)
-		}
-	}
-
-	// Process the arguments
-	argIndex := 0
-	for i, arg := range args {
-		if argIndex < len(specifierIndices) {
-			// If this argument corresponds to a %i specifier
-			if s, ok := arg.(string); ok {
-				processedArgs[i] = p.QuoteIdentifier(s)
-			} else {
-				processedArgs[i] = arg
-			}
-			argIndex++
-		} else {
-			processedArgs[i] = arg
-		}
-	}
-
-	// Replace %i with %s in the format string
-	processedFormat := strings.ReplaceAll(format, "%i", "%s")
-
-	return processedArgs, processedFormat
-}
-
 func (p PostgresDialect) Serial() string            { return PsqlSerial }
 func (p PostgresDialect) SmallSerial() string       { return PsqlSmallSerial }
 func (p PostgresDialect) BigSerial() string         { return PsqlBigSerial }
@@ -175,5 +136,282 @@ func (p PostgresDialect) Placeholder(index int) string {
 }
 
 func (p PostgresDialect) QuoteIdentifier(name string) string {
-	return fmt.Sprintf("\"%s\"", name)
+	return fmt.Sprintf("\"%s\"", strings.ReplaceAll(name, "\"", "\"\""))
+}
+
+// TransactionalDDL reports true: PostgreSQL can roll back schema changes made inside a transaction
+func (p PostgresDialect) TransactionalDDL() bool {
+	return true
+}
+
+// AdvisoryLock tries to take the named lock without waiting; the query returns true if it was taken
+func (p PostgresDialect) AdvisoryLock(name string) (string, []interface{}) {
+	return "SELECT pg_try_advisory_lock($1)", []interface{}{advisoryLockKey(name)}
+}
+
+// AdvisoryUnlock releases a lock taken with AdvisoryLock
+func (p PostgresDialect) AdvisoryUnlock(name string) (string, []interface{}) {
+	return "SELECT pg_advisory_unlock($1)", []interface{}{advisoryLockKey(name)}
+}
+
+// advisoryLockKey maps a lock name onto the bigint key PostgreSQL advisory locks are identified by
+func advisoryLockKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return int64(h.Sum64())
+}
+
+func (p PostgresDialect) AlterColumnType(table, column, columnType string) string {
+	return p.Sprintd("ALTER TABLE %i ALTER COLUMN %i TYPE %s", table, column, columnType)
+}
+
+// DropIndex drops an index; PostgreSQL index names are unique per schema, so the table isn't needed
+func (p PostgresDialect) DropIndex(table, index string) string {
+	return p.Sprintd("DROP INDEX %i", index)
+}
+
+func (p PostgresDialect) DropForeignKey(table, constraint string) string {
+	return p.Sprintd("ALTER TABLE %i DROP CONSTRAINT %i", table, constraint)
+}
+
+// Limit renders a LIMIT/OFFSET clause. A negative limit or offset is left out.
+func (p PostgresDialect) Limit(limit, offset int) string {
+	var clauses []string
+	if limit >= 0 {
+		clauses = append(clauses, fmt.Sprintf("LIMIT %d", limit))
+	}
+	if offset >= 0 {
+		clauses = append(clauses, fmt.Sprintf("OFFSET %d", offset))
+	}
+	return strings.Join(clauses, " ")
+}
+
+// OnConflict renders an ON CONFLICT clause, along with the arguments bound to its WHERE guard
+func (p PostgresDialect) OnConflict(conflict Conflict) (string, []interface{}) {
+	var clause string
+	switch {
+	case conflict.Constraint != "":
+		clause = p.Sprintd(" ON CONFLICT ON CONSTRAINT %i", conflict.Constraint)
+	case len(conflict.Target) > 0:
+		target := make([]string, len(conflict.Target))
+		for i, column := range conflict.Target {
+			target[i] = p.QuoteIdentifier(column)
+		}
+		clause = fmt.Sprintf(" ON CONFLICT (%s)", strings.Join(target, ", "))
+	case conflict.DoNothing:
+		return " ON CONFLICT DO NOTHING", nil // Any unique key will do
+	default:
+		return "", nil // Nothing to resolve a conflict on; a duplicate fails the insert
+	}
+
+	if conflict.DoNothing || len(conflict.Update) == 0 {
+		return clause + " DO NOTHING", nil
+	}
+	updates := make([]string, len(conflict.Update))
+	for i, column := range conflict.Update {
+		updates[i] = p.Sprintd("%i = %s", column, p.Inserted(column))
+	}
+	clause += " DO UPDATE SET " + strings.Join(updates, ", ")
+	if conflict.Where != "" {
+		return clause + " WHERE " + conflict.Where, conflict.WhereArgs
+	}
+	return clause, nil
+}
+
+// Inserted refers to the value an upsert tried to insert into column
+func (p PostgresDialect) Inserted(column string) string {
+	return "EXCLUDED." + p.QuoteIdentifier(column)
+}
+
+// Returning renders a RETURNING clause that hands back every column of the rows a statement wrote
+func (p PostgresDialect) Returning() string {
+	return " RETURNING *"
+}
+
+// MaxParameters is the most bind parameters one statement can take; the wire protocol counts them in 16 bits
+func (p PostgresDialect) MaxParameters() int {
+	return 65535
+}
+
+// CopyIn renders a COPY FROM STDIN statement for lib/pq. Prepared in a transaction, each Exec
+// with arguments streams a row and a final Exec without any flushes them.
+func (p PostgresDialect) CopyIn(table string, columns []string) string {
+	return pq.CopyIn(table, columns...)
+}
+
+// Retryable reports whether err is a serialization failure (40001) or a deadlock (40P01), after
+// which the whole transaction can be run again
+func (p PostgresDialect) Retryable(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	return pqErr.Code == "40001" || pqErr.Code == "40P01"
+}
+
+// postgresErrorKinds maps SQLSTATE codes to the kinds of DatabaseError they are translated to
+var postgresErrorKinds = map[pq.ErrorCode]error{
+	"23505": ErrUniqueViolation,
+	"23503": ErrForeignKeyViolation,
+	"23502": ErrNotNullViolation,
+	"23514": ErrCheckViolation,
+	"40001": ErrSerialization,
+	"40P01": ErrSerialization,
+}
+
+// TranslateError wraps a *pq.Error for a constraint violation or serialization failure in a
+// *DatabaseError. Other errors are returned as they are.
+func (p PostgresDialect) TranslateError(err error) error {
+	var pqErr *pq.Error
+	var dbErr *DatabaseError
+	if !errors.As(err, &pqErr) || errors.As(err, &dbErr) {
+		return err
+	}
+	kind, ok := postgresErrorKinds[pqErr.Code]
+	if !ok {
+		return err
+	}
+
+	column := pqErr.Column
+	if column == "" {
+		column = keyColumn(pqErr.Detail) // E.g., Key (user_email)=(jane@example.com) already exists.
+	}
+	return &DatabaseError{Kind: kind, Constraint: pqErr.Constraint, Table: pqErr.Table, Column: column, Err: err}
+}
+
+// TimeoutSettings sets statement_timeout and lock_timeout, in milliseconds, for the session. A zero
+// timeout is left at the server's setting.
+func (p PostgresDialect) TimeoutSettings(statement, lock time.Duration) []string {
+	var settings []string
+	if statement > 0 {
+		settings = append(settings, fmt.Sprintf("SET statement_timeout = %d", max(statement.Milliseconds(), 1)))
+	}
+	if lock > 0 {
+		settings = append(settings, fmt.Sprintf("SET lock_timeout = %d", max(lock.Milliseconds(), 1)))
+	}
+	return settings
+}
+
+// Connector opens connections through lib/pq, from a key=value or postgres:// DSN
+func (p PostgresDialect) Connector(dsn string) (driver.Connector, error) {
+	return pq.NewConnector(dsn)
+}
+
+func (p PostgresDialect) TablesQuery() string {
+	return `SELECT table_name
+FROM information_schema.tables
+WHERE table_schema = current_schema() AND table_type = 'BASE TABLE'
+ORDER BY table_name`
+}
+
+func (p PostgresDialect) ColumnsQuery() string {
+	return `SELECT a.attname, format_type(a.atttypid, a.atttypmod),
+	COALESCE(pg_get_expr(d.adbin, d.adrelid) LIKE 'nextval(%', false)
+FROM pg_catalog.pg_attribute a
+JOIN pg_catalog.pg_class c ON c.oid = a.attrelid
+JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+LEFT JOIN pg_catalog.pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+WHERE c.relname = $1 AND n.nspname = current_schema() AND a.attnum > 0 AND NOT a.attisdropped
+ORDER BY a.attnum`
+}
+
+func (p PostgresDialect) PrimaryKeyQuery() string {
+	return `SELECT kcu.column_name
+FROM information_schema.table_constraints tc
+JOIN information_schema.key_column_usage kcu
+	ON kcu.constraint_schema = tc.constraint_schema AND kcu.constraint_name = tc.constraint_name
+WHERE tc.constraint_type = 'PRIMARY KEY' AND tc.table_schema = current_schema() AND tc.table_name = $1
+ORDER BY kcu.ordinal_position`
+}
+
+func (p PostgresDialect) IndexesQuery() string {
+	return `SELECT i.relname, a.attname, ix.indisunique
+FROM pg_catalog.pg_index ix
+JOIN pg_catalog.pg_class t ON t.oid = ix.indrelid
+JOIN pg_catalog.pg_class i ON i.oid = ix.indexrelid
+JOIN pg_catalog.pg_namespace n ON n.oid = t.relnamespace
+JOIN LATERAL unnest(ix.indkey) WITH ORDINALITY AS k(attnum, position) ON true
+JOIN pg_catalog.pg_attribute a ON a.attrelid = t.oid AND a.attnum = k.attnum
+WHERE t.relname = $1 AND n.nspname = current_schema() AND NOT ix.indisprimary
+ORDER BY i.relname, k.position`
+}
+
+func (p PostgresDialect) ForeignKeysQuery() string {
+	return `SELECT kcu.column_name, ccu.table_name, ccu.column_name, tc.constraint_name
+FROM information_schema.table_constraints tc
+JOIN information_schema.key_column_usage kcu
+	ON kcu.constraint_schema = tc.constraint_schema AND kcu.constraint_name = tc.constraint_name
+JOIN information_schema.constraint_column_usage ccu
+	ON ccu.constraint_schema = tc.constraint_schema AND ccu.constraint_name = tc.constraint_name
+WHERE tc.constraint_type = 'FOREIGN KEY' AND tc.table_schema = current_schema() AND tc.table_name = $1
+ORDER BY tc.constraint_name, kcu.ordinal_position`
+}
+
+// psqlTypeNames maps the names format_type reports onto the constants the dialect writes
+var psqlTypeNames = map[string]string{
+	"bigint":                      PsqlBigInt,
+	"integer":                     PsqlInt,
+	"smallint":                    PsqlSmallInt,
+	"boolean":                     PsqlBoolean,
+	"text":                        PsqlText,
+	"real":                        PsqlReal,
+	"double precision":            PsqlDoublePrecision,
+	"money":                       PsqlMoney,
+	"timestamp without time zone": PsqlTimestamp,
+	"timestamp with time zone":    PsqlTimestampTz,
+	"date":                        PsqlDate,
+	"time without time zone":      PsqlTime,
+	"time with time zone":         PsqlTimeTz,
+	"interval":                    PsqlInterval,
+	"bytea":                       PsqlBytea,
+	"uuid":                        PsqlUuid,
+	"json":                        PsqlJson,
+	"jsonb":                       PsqlJsonB,
+	"xml":                         PsqlXml,
+	"txid_snapshot":               PsqlTxidSnapshot,
+}
+
+// psqlSerialTypes maps integer types with a sequence default onto their SERIAL shorthand
+var psqlSerialTypes = map[string]string{
+	PsqlInt:      PsqlSerial,
+	PsqlSmallInt: PsqlSmallSerial,
+	PsqlBigInt:   PsqlBigSerial,
+}
+
+var psqlParameterizedType = regexp.MustCompile(`^([a-z ]+)\((\d+)(?:,\s*(\d+))?\)$`)
+
+func (p PostgresDialect) ColumnType(columnType string, autoIncrement bool) string {
+	columnType = strings.ToLower(strings.TrimSpace(columnType))
+
+	// Arrays are reported as their element type followed by []
+	if strings.HasSuffix(columnType, "[]") {
+		return p.ColumnType(strings.TrimSuffix(columnType, "[]"), false) + "[]"
+	}
+
+	normalized := strings.ToUpper(columnType)
+	if name, ok := psqlTypeNames[columnType]; ok {
+		normalized = name
+	} else if match := psqlParameterizedType.FindStringSubmatch(columnType); match != nil {
+		switch match[1] {
+		case "character varying":
+			normalized = fmt.Sprintf("VARCHAR(%s)", match[2])
+		case "character":
+			normalized = fmt.Sprintf("CHAR(%s)", match[2])
+		case "bit":
+			normalized = fmt.Sprintf("BIT(%s)", match[2])
+		case "bit varying":
+			normalized = fmt.Sprintf("VARBIT(%s)", match[2])
+		case "numeric":
+			scale := match[3]
+			if scale == "" {
+				scale = "0"
+			}
+			normalized = fmt.Sprintf("NUMERIC(%s, %s)", match[2], scale)
+		}
+	}
+
+	if serial, ok := psqlSerialTypes[normalized]; ok && autoIncrement {
+		return serial
+	}
+	return normalized
 }
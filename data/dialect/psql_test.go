@@ -1 +1,240 @@
 package dialect
+
+import (
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPostgresSprintd(t *testing.T) {
+	d := PostgresDialect{}
+	tests := []struct {
+		name     string
+		format   string
+		args     []interface{}
+		expected string
+	}{
+		{
+			name:     "Identifiers only",
+			format:   "DROP TABLE %i",
+			args:     []interface{}{"user"},
+			expected: "DROP TABLE \"user\"",
+		},
+		{
+			name:     "Identifiers mixed with plain strings",
+			format:   "ALTER TABLE %i ADD COLUMN %i %s",
+			args:     []interface{}{"user", "status", "VARCHAR(20)"},
+			expected: "ALTER TABLE \"user\" ADD COLUMN \"status\" VARCHAR(20)",
+		},
+		{
+			name:     "Placeholder after identifiers",
+			format:   "SELECT * FROM %i WHERE %i = %s",
+			args:     []interface{}{"user", "user_id", d.Placeholder(1)},
+			expected: "SELECT * FROM \"user\" WHERE \"user_id\" = $1",
+		},
+		{
+			name:     "Plain string before identifier",
+			format:   "%s = EXCLUDED.%i",
+			args:     []interface{}{"\"role_name\"", "role_name"},
+			expected: "\"role_name\" = EXCLUDED.\"role_name\"",
+		},
+		{
+			name:     "Escaped percent and numeric verbs",
+			format:   "%i LIKE 'a%%' LIMIT %d",
+			args:     []interface{}{"name", 10},
+			expected: "\"name\" LIKE 'a%' LIMIT 10",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, d.Sprintd(tt.format, tt.args...))
+
+			var builder strings.Builder
+			_, err := d.Fprintd(&builder, tt.format, tt.args...)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, builder.String())
+		})
+	}
+}
+
+func TestPostgresColumnType(t *testing.T) {
+	d := PostgresDialect{}
+	tests := []struct {
+		columnType    string
+		autoIncrement bool
+		expected      string
+	}{
+		{"integer", true, PsqlSerial},
+		{"smallint", true, PsqlSmallSerial},
+		{"bigint", false, PsqlBigInt},
+		{"character varying(32)", false, d.VarChar(32)},
+		{"character(32)", false, d.Char(32)},
+		{"numeric(10,2)", false, d.Numeric(10, 2)},
+		{"timestamp without time zone", false, PsqlTimestamp},
+		{"time with time zone", false, PsqlTimeTz},
+		{"integer[]", false, "INTEGER[]"},
+		{"tsvector", false, PsqlTsVector},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.columnType, func(t *testing.T) {
+			assert.Equal(t, tt.expected, d.ColumnType(tt.columnType, tt.autoIncrement))
+		})
+	}
+}
+
+func TestPostgresLimit(t *testing.T) {
+	d := PostgresDialect{}
+	assert.Equal(t, "", d.Limit(-1, -1))
+	assert.Equal(t, "LIMIT 10", d.Limit(10, -1))
+	assert.Equal(t, "LIMIT 10 OFFSET 20", d.Limit(10, 20))
+	assert.Equal(t, "OFFSET 20", d.Limit(-1, 20))
+	assert.Equal(t, "\"odd\"\"name\"", d.QuoteIdentifier("odd\"name"))
+}
+
+func TestPostgresOnConflict(t *testing.T) {
+	d := PostgresDialect{}
+	tests := []struct {
+		name     string
+		conflict Conflict
+		expected string
+		args     []interface{}
+	}{
+		{
+			name:     "No target",
+			conflict: Conflict{Update: []string{"name"}},
+			expected: "",
+		},
+		{
+			name:     "Update on a key",
+			conflict: Conflict{Target: []string{"id"}, Update: []string{"name", "email"}},
+			expected: ` ON CONFLICT ("id") DO UPDATE SET "name" = EXCLUDED."name", "email" = EXCLUDED."email"`,
+		},
+		{
+			name:     "Nothing to update",
+			conflict: Conflict{Target: []string{"org_id", "id"}},
+			expected: ` ON CONFLICT ("org_id", "id") DO NOTHING`,
+		},
+		{
+			name:     "Do nothing on any key",
+			conflict: Conflict{DoNothing: true, Update: []string{"name"}},
+			expected: ` ON CONFLICT DO NOTHING`,
+		},
+		{
+			name:     "Named constraint",
+			conflict: Conflict{Constraint: "uq_role_name", Update: []string{"name"}},
+			expected: ` ON CONFLICT ON CONSTRAINT "uq_role_name" DO UPDATE SET "name" = EXCLUDED."name"`,
+		},
+		{
+			name: "Guarded update",
+			conflict: Conflict{
+				Target:    []string{"id"},
+				Update:    []string{"name", "updated_at"},
+				Where:     `"t"."updated_at" < EXCLUDED."updated_at" AND "t"."locked" = $3`,
+				WhereArgs: []interface{}{false},
+			},
+			expected: ` ON CONFLICT ("id") DO UPDATE SET "name" = EXCLUDED."name", "updated_at" = EXCLUDED."updated_at"` +
+				` WHERE "t"."updated_at" < EXCLUDED."updated_at" AND "t"."locked" = $3`,
+			args: []interface{}{false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clause, args := d.OnConflict(tt.conflict)
+			assert.Equal(t, tt.expected, clause)
+			assert.Equal(t, tt.args, args)
+		})
+	}
+}
+
+func TestPostgresRetryable(t *testing.T) {
+	d := PostgresDialect{}
+	assert.True(t, d.Retryable(&pq.Error{Code: "40001"}))
+	assert.True(t, d.Retryable(fmt.Errorf("upsert failed: %w", &pq.Error{Code: "40P01"})))
+	assert.False(t, d.Retryable(&pq.Error{Code: "23505"}))
+	assert.False(t, d.Retryable(errors.New("connection refused")))
+}
+
+func TestPostgresTranslateError(t *testing.T) {
+	d := PostgresDialect{}
+	tests := []struct {
+		name     string
+		err      *pq.Error
+		kind     error
+		expected DatabaseError
+	}{
+		{
+			name: "unique",
+			err: &pq.Error{Code: "23505", Table: "user", Constraint: "uq_user_on_user_email",
+				Detail: "Key (user_email)=(jane@example.com) already exists."},
+			kind:     ErrUniqueViolation,
+			expected: DatabaseError{Constraint: "uq_user_on_user_email", Table: "user", Column: "user_email"},
+		},
+		{
+			name: "foreign key",
+			err: &pq.Error{Code: "23503", Table: "user", Constraint: "fk_user_user_role",
+				Detail: "Key (user_role)=(9) is not present in table \"user_role\"."},
+			kind:     ErrForeignKeyViolation,
+			expected: DatabaseError{Constraint: "fk_user_user_role", Table: "user", Column: "user_role"},
+		},
+		{
+			name:     "not null",
+			err:      &pq.Error{Code: "23502", Table: "user", Column: "user_email"},
+			kind:     ErrNotNullViolation,
+			expected: DatabaseError{Table: "user", Column: "user_email"},
+		},
+		{
+			name:     "check",
+			err:      &pq.Error{Code: "23514", Table: "user", Constraint: "chk_user_age"},
+			kind:     ErrCheckViolation,
+			expected: DatabaseError{Constraint: "chk_user_age", Table: "user"},
+		},
+		{
+			name: "serialization",
+			err:  &pq.Error{Code: "40001"},
+			kind: ErrSerialization,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := d.TranslateError(tt.err)
+			assert.ErrorIs(t, err, tt.kind)
+			var pqErr *pq.Error
+			assert.ErrorAs(t, err, &pqErr)
+
+			var dbErr *DatabaseError
+			if assert.ErrorAs(t, err, &dbErr) {
+				assert.Equal(t, tt.expected.Constraint, dbErr.Constraint)
+				assert.Equal(t, tt.expected.Table, dbErr.Table)
+				assert.Equal(t, tt.expected.Column, dbErr.Column)
+			}
+			assert.Same(t, err, d.TranslateError(err))
+		})
+	}
+
+	other := &pq.Error{Code: "42P01"}
+	assert.Same(t, other, d.TranslateError(other))
+	assert.NoError(t, d.TranslateError(nil))
+}
+
+func TestPostgresTimeoutSettings(t *testing.T) {
+	d := PostgresDialect{}
+	assert.Equal(t,
+		[]string{"SET statement_timeout = 5000", "SET lock_timeout = 500"},
+		d.TimeoutSettings(5*time.Second, 500*time.Millisecond))
+	assert.Empty(t, d.TimeoutSettings(0, 0))
+}
+
+func TestPostgresConnector(t *testing.T) {
+	d := PostgresDialect{}
+	connector, err := d.Connector("user=app dbname=app sslmode=disable")
+	assert.NoError(t, err)
+	assert.IsType(t, &pq.Driver{}, connector.Driver())
+}
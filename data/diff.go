@@ -0,0 +1,379 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"gormless/data/dialect"
+	"sort"
+	"strings"
+)
+
+// ChangeKind is the kind of difference a SchemaChange describes. Kinds are declared in the order
+// their migrations can safely be applied in.
+type ChangeKind int
+
+const (
+	TableAdded ChangeKind = iota
+	ForeignKeyRemoved
+	IndexRemoved
+	ColumnAdded
+	ColumnTypeChanged
+	ColumnRemoved
+	PrimaryKeyChanged
+	IndexAdded
+	ForeignKeyAdded
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case TableAdded:
+		return "table added"
+	case ForeignKeyRemoved:
+		return "foreign key removed"
+	case IndexRemoved:
+		return "index removed"
+	case ColumnAdded:
+		return "column added"
+	case ColumnTypeChanged:
+		return "column type changed"
+	case ColumnRemoved:
+		return "column removed"
+	case PrimaryKeyChanged:
+		return "primary key changed"
+	case IndexAdded:
+		return "index added"
+	case ForeignKeyAdded:
+		return "foreign key added"
+	default:
+		return fmt.Sprintf("ChangeKind(%d)", int(k))
+	}
+}
+
+// SchemaChange is one difference between a table defined in Go and the same table in the database
+type SchemaChange struct {
+	Kind        ChangeKind
+	Table       string
+	Column      string    // The column the change is about, if any
+	Index       *Index    // The index the change is about, if any
+	From        string    // What the database has, e.g. the live column type
+	To          string    // What the definition wants
+	Destructive bool      // Applying the change loses data
+	Migration   Migration // Reconciles the difference; nil if it has to be done by hand
+}
+
+func (c SchemaChange) String() string {
+	subject := c.Table
+	if c.Column != "" {
+		subject += "." + c.Column
+	}
+	if c.Index != nil {
+		subject += " (" + strings.Join(c.Index.Columns, ", ") + ")"
+	}
+	if c.From != "" || c.To != "" {
+		return fmt.Sprintf("%s: %s %s -> %s", c.Kind, subject, c.From, c.To)
+	}
+	return fmt.Sprintf("%s: %s", c.Kind, subject)
+}
+
+// TableDiff lists the changes needed to turn a live table into its definition
+type TableDiff struct {
+	Table   Table // The desired definition
+	Changes []SchemaChange
+}
+
+// Empty reports whether the live table already matches its definition
+func (d TableDiff) Empty() bool {
+	return len(d.Changes) == 0
+}
+
+// Migrations returns the migrations that reconcile the table, in a safe order. Changes that have
+// to be made by hand, such as primary key changes, are left out.
+func (d TableDiff) Migrations() []Migration {
+	var migrations []Migration
+	for _, change := range d.Changes {
+		if change.Migration != nil {
+			migrations = append(migrations, change.Migration)
+		}
+	}
+	return migrations
+}
+
+// Migration combines Migrations into a single migration, ready to be registered.
+//
+// The generated SQL depends on the live schema at the time of the diff. Once applied, a migration
+// generated from a diff will checksum differently on the next run, so register the reviewed SQL
+// (e.g. from a DryRunSession) rather than regenerating the diff on every deploy.
+func (d TableDiff) Migration() Migration {
+	migrations := d.Migrations()
+	return func(table Table, session ISession) error {
+		for _, migration := range migrations {
+			if err := migration(table, session); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// DiffTable compares a table definition with the live database and returns what differs
+func DiffTable(session ISession, def TableDef) (TableDiff, error) {
+	desired := def()
+
+	live, err := IntrospectTable(session, desired.Name)
+	if errors.Is(err, ErrTableNotFound) {
+		return TableDiff{
+			Table: desired,
+			Changes: []SchemaChange{{
+				Kind:  TableAdded,
+				Table: desired.Name,
+				Migration: func(table Table, session ISession) error {
+					return CreateTable(session, desired)
+				},
+			}},
+		}, nil
+	}
+	if err != nil {
+		return TableDiff{Table: desired}, err
+	}
+
+	return CompareTables(session.Dialect(), desired, live), nil
+}
+
+// CompareTables returns the changes that turn live into desired. Column types are compared after
+// normalizing both through the dialect, so "varchar(32)" and "VARCHAR(32)" are the same type.
+func CompareTables(d dialect.Dialect, desired Table, live Table) TableDiff {
+	diff := TableDiff{Table: desired}
+
+	desiredColumns := tableColumns(desired)
+	liveColumns := tableColumns(live)
+	liveByName := make(map[string]Column, len(liveColumns))
+	for _, column := range liveColumns {
+		liveByName[column.Name] = column
+	}
+	desiredByName := make(map[string]Column, len(desiredColumns))
+	for _, column := range desiredColumns {
+		desiredByName[column.Name] = column
+	}
+
+	// Columns
+	for _, column := range desiredColumns {
+		liveColumn, ok := liveByName[column.Name]
+		if !ok {
+			diff.Changes = append(diff.Changes, SchemaChange{
+				Kind:      ColumnAdded,
+				Table:     desired.Name,
+				Column:    column.Name,
+				To:        typeOf(column),
+				Migration: AddColumn(desired, Column{Name: column.Name, Type: column.Type}),
+			})
+			continue
+		}
+
+		if column.Type != nil && liveColumn.Type != nil &&
+			normalizeType(d, *column.Type) != normalizeType(d, *liveColumn.Type) {
+			diff.Changes = append(diff.Changes, SchemaChange{
+				Kind:      ColumnTypeChanged,
+				Table:     desired.Name,
+				Column:    column.Name,
+				From:      *liveColumn.Type,
+				To:        *column.Type,
+				Migration: ModifyColumn(desired, Column{Name: column.Name}, Column{Type: column.Type}),
+			})
+		}
+	}
+	for _, column := range liveColumns {
+		if _, ok := desiredByName[column.Name]; !ok {
+			diff.Changes = append(diff.Changes, SchemaChange{
+				Kind:        ColumnRemoved,
+				Table:       desired.Name,
+				Column:      column.Name,
+				From:        typeOf(column),
+				Destructive: true,
+				Migration:   RemoveColumn(column),
+			})
+		}
+	}
+
+	// Primary key
+	desiredKey := primaryKey(desiredColumns)
+	liveKey := primaryKey(liveColumns)
+	if desiredKey != liveKey {
+		diff.Changes = append(diff.Changes, SchemaChange{
+			Kind:  PrimaryKeyChanged,
+			Table: desired.Name,
+			From:  liveKey,
+			To:    desiredKey,
+		})
+	}
+
+	// Indexes are matched on their columns and uniqueness, since generated names vary
+	liveIndexes := make(map[string]Index)
+	for _, index := range tableIndexes(live) {
+		// MySQL backs every foreign key with an index of the same name
+		if isForeignKeyIndex(index, liveColumns) {
+			continue
+		}
+		liveIndexes[indexKey(index)] = index
+	}
+	desiredIndexes := make(map[string]Index)
+	for _, index := range tableIndexes(desired) {
+		desiredIndexes[indexKey(index)] = index
+	}
+	for _, key := range sortedKeys(desiredIndexes) {
+		if _, ok := liveIndexes[key]; !ok {
+			index := desiredIndexes[key]
+			diff.Changes = append(diff.Changes, SchemaChange{
+				Kind:      IndexAdded,
+				Table:     desired.Name,
+				Index:     &index,
+				Migration: AddIndex(index),
+			})
+		}
+	}
+	for _, key := range sortedKeys(liveIndexes) {
+		if _, ok := desiredIndexes[key]; !ok {
+			index := liveIndexes[key]
+			diff.Changes = append(diff.Changes, SchemaChange{
+				Kind:      IndexRemoved,
+				Table:     desired.Name,
+				Index:     &index,
+				Migration: DropIndex(index),
+			})
+		}
+	}
+
+	// Foreign keys
+	for _, column := range desiredColumns {
+		liveColumn, exists := liveByName[column.Name]
+		wanted := foreignKeyTarget(column)
+		current := ""
+		if exists {
+			current = foreignKeyTarget(liveColumn)
+		}
+		if wanted == current {
+			continue
+		}
+		if current != "" {
+			diff.Changes = append(diff.Changes, SchemaChange{
+				Kind:      ForeignKeyRemoved,
+				Table:     desired.Name,
+				Column:    column.Name,
+				From:      current,
+				Migration: DropForeignKey(liveColumn),
+			})
+		}
+		if wanted != "" {
+			diff.Changes = append(diff.Changes, SchemaChange{
+				Kind:      ForeignKeyAdded,
+				Table:     desired.Name,
+				Column:    column.Name,
+				To:        wanted,
+				Migration: AddForeignKey(column),
+			})
+		}
+	}
+
+	for _, column := range liveColumns {
+		if _, ok := desiredByName[column.Name]; !ok && foreignKeyTarget(column) != "" {
+			diff.Changes = append(diff.Changes, SchemaChange{
+				Kind:      ForeignKeyRemoved,
+				Table:     desired.Name,
+				Column:    column.Name,
+				From:      foreignKeyTarget(column),
+				Migration: DropForeignKey(column),
+			})
+		}
+	}
+
+	sort.SliceStable(diff.Changes, func(i, j int) bool {
+		return diff.Changes[i].Kind < diff.Changes[j].Kind
+	})
+	return diff
+}
+
+func tableColumns(table Table) []Column {
+	if table.Columns == nil {
+		return nil
+	}
+	return *table.Columns
+}
+
+// tableIndexes returns the table's explicit indexes plus one for each Indexed column
+func tableIndexes(table Table) []Index {
+	var indexes []Index
+	if table.Indexes != nil {
+		indexes = append(indexes, *table.Indexes...)
+	}
+	for _, column := range tableColumns(table) {
+		if !column.Indexed {
+			continue
+		}
+		index := Index{Name: indexName(table, column), Columns: []string{column.Name}}
+		covered := false
+		for _, existing := range indexes {
+			if indexKey(existing) == indexKey(index) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			indexes = append(indexes, index)
+		}
+	}
+	return indexes
+}
+
+func indexKey(index Index) string {
+	key := strings.Join(index.Columns, ",")
+	if index.Unique {
+		key += " unique"
+	}
+	return key
+}
+
+func isForeignKeyIndex(index Index, columns []Column) bool {
+	for _, column := range columns {
+		if column.ForeignKey != nil && column.ForeignKey.Name != "" && column.ForeignKey.Name == index.Name {
+			return true
+		}
+	}
+	return false
+}
+
+func foreignKeyTarget(column Column) string {
+	fk := column.ForeignKey
+	if fk == nil || fk.Table == nil || fk.Column == nil {
+		return ""
+	}
+	return fk.Table.Name + "(" + fk.Column.Name + ")"
+}
+
+func primaryKey(columns []Column) string {
+	var key []string
+	for _, column := range columns {
+		if column.PrimaryKey {
+			key = append(key, column.Name)
+		}
+	}
+	return strings.Join(key, ", ")
+}
+
+func typeOf(column Column) string {
+	if column.Type == nil {
+		return ""
+	}
+	return *column.Type
+}
+
+func normalizeType(d dialect.Dialect, columnType string) string {
+	return d.ColumnType(strings.Join(strings.Fields(columnType), " "), false)
+}
+
+func sortedKeys(indexes map[string]Index) []string {
+	keys := make([]string, 0, len(indexes))
+	for key := range indexes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
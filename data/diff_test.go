@@ -0,0 +1,105 @@
+package data
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"gormless/data/dialect"
+	"regexp"
+	"testing"
+)
+
+func TestCompareTables(t *testing.T) {
+	roleTable := Table{Name: "user_role"}
+	desired := Table{
+		Name: "user",
+		Columns: &[]Column{
+			{Name: "user_id", Type: stringPtr(dialect.PsqlSerial), PrimaryKey: true},
+			{Name: "user_first", Type: stringPtr("varchar(64)")},
+			{Name: "user_email", Type: stringPtr("VARCHAR(64)"), Indexed: true},
+			{Name: "user_role", Type: stringPtr(dialect.PsqlInt), ForeignKey: &ForeignKey{Table: &roleTable, Column: &Column{Name: "role_id"}}},
+		},
+	}
+	live := Table{
+		Name: "user",
+		Columns: &[]Column{
+			{Name: "user_id", Type: stringPtr(dialect.PsqlSerial), PrimaryKey: true},
+			{Name: "user_first", Type: stringPtr("VARCHAR(32)")},
+			{Name: "user_email", Type: stringPtr("VARCHAR(64)")},
+			{Name: "legacy", Type: stringPtr(dialect.PsqlText), Indexed: true},
+		},
+		Indexes: &[]Index{{Name: "idx_legacy", Columns: []string{"legacy"}}},
+	}
+
+	diff := CompareTables(dialect.PostgresDialect{}, desired, live)
+
+	var kinds []ChangeKind
+	for _, change := range diff.Changes {
+		kinds = append(kinds, change.Kind)
+	}
+	assert.Equal(t, []ChangeKind{IndexRemoved, ColumnAdded, ColumnTypeChanged, ColumnRemoved, IndexAdded, ForeignKeyAdded}, kinds)
+	assert.Equal(t, "column type changed: user.user_first VARCHAR(32) -> varchar(64)", diff.Changes[2].String())
+	assert.True(t, diff.Changes[3].Destructive)
+	assert.Len(t, diff.Migrations(), 6)
+
+	// The generated migrations, rendered through a dry run
+	dryRun := NewDryRunSession(dialect.PostgresDialect{})
+	assert.NoError(t, diff.Migration()(desired, dryRun))
+	var statements []string
+	for _, statement := range dryRun.Plan() {
+		statements = append(statements, statement.SQL)
+	}
+	assert.Equal(t, []string{
+		"DROP INDEX \"idx_legacy\"",
+		"ALTER TABLE \"user\" ADD COLUMN \"user_role\" INTEGER",
+		"BEGIN",
+		"ALTER TABLE \"user\" ALTER COLUMN \"user_first\" TYPE varchar(64)",
+		"COMMIT",
+		"ALTER TABLE \"user\" DROP COLUMN \"legacy\"",
+		"CREATE INDEX \"idx_user_on_user_email\" ON \"user\" (\"user_email\")",
+		"ALTER TABLE \"user\" ADD FOREIGN KEY (\"user_role\") REFERENCES \"user_role\" (\"role_id\")",
+	}, statements)
+
+	assert.True(t, CompareTables(dialect.PostgresDialect{}, desired, desired).Empty())
+}
+
+func TestCompareTablesIgnoresMySQLForeignKeyIndexes(t *testing.T) {
+	roleTable := Table{Name: "user_role"}
+	fk := &ForeignKey{Table: &roleTable, Column: &Column{Name: "role_id"}}
+	desired := Table{
+		Name:    "user",
+		Columns: &[]Column{{Name: "user_role", Type: stringPtr("INT"), ForeignKey: fk}},
+	}
+	live := Table{
+		Name: "user",
+		Columns: &[]Column{{Name: "user_role", Type: stringPtr("INT"), Indexed: true,
+			ForeignKey: &ForeignKey{Table: &roleTable, Column: &Column{Name: "role_id"}, Name: "user_ibfk_1"}}},
+		Indexes: &[]Index{{Name: "user_ibfk_1", Columns: []string{"user_role"}}},
+	}
+
+	diff := CompareTables(dialect.MySQLDialect{}, desired, live)
+	assert.True(t, diff.Empty(), "unexpected changes: %v", diff.Changes)
+}
+
+func TestDiffTableMissingTable(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	session := &Session{DB: db}
+	session.SQLDialect = dialect.PostgresDialect{}
+
+	mock.ExpectQuery(regexp.QuoteMeta(session.SQLDialect.ColumnsQuery())).WithArgs("widget").
+		WillReturnRows(sqlmock.NewRows([]string{"attname", "format_type", "auto_increment"}))
+
+	diff, err := DiffTable(session, func() Table {
+		return Table{Name: "widget", Columns: &[]Column{{Name: "id", Type: stringPtr(dialect.PsqlSerial), PrimaryKey: true}}}
+	})
+
+	assert.NoError(t, err)
+	if assert.Len(t, diff.Changes, 1) {
+		assert.Equal(t, TableAdded, diff.Changes[0].Kind)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
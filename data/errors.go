@@ -0,0 +1,63 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"gormless/data/dialect"
+)
+
+// Kinds of database error the dialects recognise. Session and TxSession return such errors as a
+// *DatabaseError, which matches its kind with errors.Is and names the constraint and column
+// involved, where the database reports them.
+//
+// E.g.,
+//
+//	err := dao.InsertStruct(user)
+//	var dbErr *data.DatabaseError
+//	if errors.Is(err, data.ErrUniqueViolation) && errors.As(err, &dbErr) {
+//		return http.StatusConflict, fmt.Sprintf("%s is already taken", dbErr.Column)
+//	}
+var (
+	ErrUniqueViolation     = dialect.ErrUniqueViolation
+	ErrForeignKeyViolation = dialect.ErrForeignKeyViolation
+	ErrNotNullViolation    = dialect.ErrNotNullViolation
+	ErrCheckViolation      = dialect.ErrCheckViolation
+	ErrSerialization       = dialect.ErrSerialization
+)
+
+// DatabaseError is a driver error that the session's dialect has recognised
+type DatabaseError = dialect.DatabaseError
+
+// ErrNotFound is returned when a lookup for one row, e.g. FindOne, finds none. The error returned
+// also matches sql.ErrNoRows.
+var ErrNotFound = errors.New("not found")
+
+// errNoRows is returned for a missing row; it matches both ErrNotFound and sql.ErrNoRows
+var errNoRows error = notFoundError{}
+
+type notFoundError struct{}
+
+func (notFoundError) Error() string {
+	return sql.ErrNoRows.Error()
+}
+
+func (notFoundError) Unwrap() []error {
+	return []error{ErrNotFound, sql.ErrNoRows}
+}
+
+// StatementError reports a statement that failed, a schema change or a write, with the statement
+// and the table it was run against. It unwraps to the database's error, so errors.Is still matches, e.g., ErrUniqueViolation.
+type StatementError struct {
+	Table     string
+	Statement string
+	Err       error
+}
+
+func (e *StatementError) Error() string {
+	return fmt.Sprintf("%s: %v (running %s)", e.Table, e.Err, e.Statement)
+}
+
+func (e *StatementError) Unwrap() error {
+	return e.Err
+}
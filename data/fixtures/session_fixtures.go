@@ -1,8 +1,11 @@
 package fixtures
 
 import (
+	"context"
 	"database/sql"
 	"github.com/stretchr/testify/mock"
+	"gormless/data"
+	"gormless/data/dialect"
 )
 
 type MockDB struct {
@@ -24,6 +27,31 @@ type MockSession struct {
 	*MockDB
 }
 
+var _ data.ISession = (*MockSession)(nil)
+
+func (m *MockSession) Dialect() dialect.Dialect {
+	args := m.Called()
+	return args.Get(0).(dialect.Dialect)
+}
+
+// WithTx runs fn with the mock itself as the transaction, unless the call is set up to fail
+func (m *MockSession) WithTx(fn func(tx data.ISession) error) error {
+	args := m.Called()
+	if err := args.Error(0); err != nil {
+		return err
+	}
+	return fn(m)
+}
+
+// WithTxOptions runs fn as WithTx does, recording the options it was given
+func (m *MockSession) WithTxOptions(options data.TxOptions, fn func(tx data.ISession) error) error {
+	args := m.Called(options)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+	return fn(m)
+}
+
 func (m *MockSession) Prepare(query string) (*sql.Stmt, error) {
 	args := m.Called(query)
 	return args.Get(0).(*sql.Stmt), args.Error(1)
@@ -63,6 +91,30 @@ func (m *MockSession) Open(dsn string) error {
 	return args.Error(0)
 }
 
+func (m *MockSession) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return m.Prepare(query)
+}
+
+func (m *MockSession) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return m.Exec(query, args...)
+}
+
+func (m *MockSession) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return m.Query(query, args...)
+}
+
+func (m *MockSession) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return m.QueryRow(query, args...)
+}
+
+func (m *MockSession) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	return m.Begin()
+}
+
+func (m *MockSession) PingContext(ctx context.Context) error {
+	return m.Ping()
+}
+
 // MockStmt is a mock for sql.Stmt
 type MockStmt struct {
 	mock.Mock
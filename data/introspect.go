@@ -0,0 +1,191 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrTableNotFound is returned when introspecting a table that doesn't exist
+var ErrTableNotFound = errors.New("table does not exist")
+
+// IntrospectTable reads a table's definition back from the live database: its columns and their
+// types, primary key, indexes and foreign keys. Column types are converted by the session's
+// dialect into the form its type methods produce, so they compare equal to a matching TableDef.
+//
+// Every index is listed in Table.Indexes; columns that have a single-column index of their own
+// are also marked Indexed.
+func IntrospectTable(session ISession, name string) (Table, error) {
+	table := Table{Name: name}
+
+	columns, err := introspectColumns(session, name)
+	if err != nil {
+		return table, err
+	}
+	if len(columns) == 0 {
+		return table, fmt.Errorf("introspecting table %s: %w", name, ErrTableNotFound)
+	}
+	table.Columns = &columns
+
+	byName := make(map[string]*Column, len(columns))
+	for i := range columns {
+		byName[columns[i].Name] = &columns[i]
+	}
+
+	err = introspectPrimaryKey(session, name, byName)
+	if err != nil {
+		return table, err
+	}
+
+	indexes, err := introspectIndexes(session, name, byName)
+	if err != nil {
+		return table, err
+	}
+	table.Indexes = &indexes
+
+	err = introspectForeignKeys(session, name, byName)
+	if err != nil {
+		return table, err
+	}
+
+	return table, nil
+}
+
+// IntrospectSchema reads every table in the session's current schema
+func IntrospectSchema(session ISession) ([]Table, error) {
+	rows, err := session.Query(session.Dialect().TablesQuery())
+	if err != nil {
+		return nil, fmt.Errorf("listing tables: %w", err)
+	}
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("listing tables: %w", err)
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("listing tables: %w", err)
+	}
+
+	tables := make([]Table, 0, len(names))
+	for _, name := range names {
+		table, err := IntrospectTable(session, name)
+		if err != nil {
+			return nil, err
+		}
+		tables = append(tables, table)
+	}
+	return tables, nil
+}
+
+func introspectColumns(session ISession, table string) ([]Column, error) {
+	dialect := session.Dialect()
+	rows, err := session.Query(dialect.ColumnsQuery(), table)
+	if err != nil {
+		return nil, fmt.Errorf("introspecting columns of %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	var columns []Column
+	for rows.Next() {
+		var name, columnType string
+		var autoIncrement bool
+		if err := rows.Scan(&name, &columnType, &autoIncrement); err != nil {
+			return nil, fmt.Errorf("introspecting columns of %s: %w", table, err)
+		}
+		normalized := dialect.ColumnType(columnType, autoIncrement)
+		columns = append(columns, Column{Name: name, Type: &normalized})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("introspecting columns of %s: %w", table, err)
+	}
+	return columns, nil
+}
+
+func introspectPrimaryKey(session ISession, table string, columns map[string]*Column) error {
+	rows, err := session.Query(session.Dialect().PrimaryKeyQuery(), table)
+	if err != nil {
+		return fmt.Errorf("introspecting primary key of %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return fmt.Errorf("introspecting primary key of %s: %w", table, err)
+		}
+		if column, ok := columns[name]; ok {
+			column.PrimaryKey = true
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("introspecting primary key of %s: %w", table, err)
+	}
+	return nil
+}
+
+func introspectIndexes(session ISession, table string, columns map[string]*Column) ([]Index, error) {
+	rows, err := session.Query(session.Dialect().IndexesQuery(), table)
+	if err != nil {
+		return nil, fmt.Errorf("introspecting indexes of %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	var indexes []Index
+	for rows.Next() {
+		var indexName, columnName string
+		var unique bool
+		if err := rows.Scan(&indexName, &columnName, &unique); err != nil {
+			return nil, fmt.Errorf("introspecting indexes of %s: %w", table, err)
+		}
+		// Rows arrive ordered by index, so a new name starts a new index
+		if len(indexes) == 0 || indexes[len(indexes)-1].Name != indexName {
+			indexes = append(indexes, Index{Name: indexName, Unique: unique})
+		}
+		index := &indexes[len(indexes)-1]
+		index.Columns = append(index.Columns, columnName)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("introspecting indexes of %s: %w", table, err)
+	}
+
+	for _, index := range indexes {
+		if len(index.Columns) != 1 {
+			continue
+		}
+		if column, ok := columns[index.Columns[0]]; ok {
+			column.Indexed = true
+		}
+	}
+	return indexes, nil
+}
+
+func introspectForeignKeys(session ISession, table string, columns map[string]*Column) error {
+	rows, err := session.Query(session.Dialect().ForeignKeysQuery(), table)
+	if err != nil {
+		return fmt.Errorf("introspecting foreign keys of %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var columnName, referencedTable, referencedColumn, constraintName string
+		if err := rows.Scan(&columnName, &referencedTable, &referencedColumn, &constraintName); err != nil {
+			return fmt.Errorf("introspecting foreign keys of %s: %w", table, err)
+		}
+		if column, ok := columns[columnName]; ok {
+			column.ForeignKey = &ForeignKey{
+				Table:  &Table{Name: referencedTable},
+				Column: &Column{Name: referencedColumn},
+				Name:   constraintName,
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("introspecting foreign keys of %s: %w", table, err)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package data
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"gormless/data/dialect"
+	"regexp"
+	"testing"
+)
+
+func TestIntrospectTable(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	session := &Session{DB: db}
+	session.SQLDialect = dialect.PostgresDialect{}
+	d := session.SQLDialect
+
+	mock.ExpectQuery(regexp.QuoteMeta(d.ColumnsQuery())).WithArgs("user").
+		WillReturnRows(sqlmock.NewRows([]string{"attname", "format_type", "auto_increment"}).
+			AddRow("user_id", "integer", true).
+			AddRow("user_first", "character varying(32)", false).
+			AddRow("user_email", "character varying(64)", false).
+			AddRow("user_role", "integer", false))
+	mock.ExpectQuery(regexp.QuoteMeta(d.PrimaryKeyQuery())).WithArgs("user").
+		WillReturnRows(sqlmock.NewRows([]string{"column_name"}).AddRow("user_id"))
+	mock.ExpectQuery(regexp.QuoteMeta(d.IndexesQuery())).WithArgs("user").
+		WillReturnRows(sqlmock.NewRows([]string{"relname", "attname", "indisunique"}).
+			AddRow("idx_user_on_user_email", "user_email", false).
+			AddRow("uq_user_name", "user_first", true).
+			AddRow("uq_user_name", "user_email", true))
+	mock.ExpectQuery(regexp.QuoteMeta(d.ForeignKeysQuery())).WithArgs("user").
+		WillReturnRows(sqlmock.NewRows([]string{"column_name", "table_name", "column_name", "constraint_name"}).
+			AddRow("user_role", "user_role", "role_id", "user_user_role_fkey"))
+
+	table, err := IntrospectTable(session, "user")
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, "user", table.Name)
+
+	columns := *table.Columns
+	if assert.Len(t, columns, 4) {
+		assert.Equal(t, dialect.PsqlSerial, *columns[0].Type)
+		assert.True(t, columns[0].PrimaryKey)
+		assert.Equal(t, "VARCHAR(32)", *columns[1].Type)
+		assert.False(t, columns[1].Indexed, "only part of a composite index")
+		assert.True(t, columns[2].Indexed)
+		assert.Equal(t, dialect.PsqlInt, *columns[3].Type)
+		if assert.NotNil(t, columns[3].ForeignKey) {
+			assert.Equal(t, "user_role", columns[3].ForeignKey.Table.Name)
+			assert.Equal(t, "role_id", columns[3].ForeignKey.Column.Name)
+		}
+	}
+	assert.Equal(t, []Index{
+		{Name: "idx_user_on_user_email", Columns: []string{"user_email"}},
+		{Name: "uq_user_name", Columns: []string{"user_first", "user_email"}, Unique: true},
+	}, *table.Indexes)
+}
+
+func TestIntrospectMissingTable(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	session := &Session{DB: db}
+	session.SQLDialect = dialect.MySQLDialect{}
+
+	mock.ExpectQuery("information_schema.COLUMNS").WithArgs("missing").
+		WillReturnRows(sqlmock.NewRows([]string{"COLUMN_NAME", "COLUMN_TYPE", "auto_increment"}))
+
+	_, err = IntrospectTable(session, "missing")
+	assert.ErrorContains(t, err, "table does not exist")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,150 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultLockName is the lock taken around schema changes when LockOptions doesn't name one
+const DefaultLockName = "gormless_schema"
+
+// lockPollInterval is how often a waiting process retries the lock
+const lockPollInterval = 100 * time.Millisecond
+
+// ErrLockTimeout is returned when another process still holds the lock after LockOptions.Timeout
+var ErrLockTimeout = errors.New("timed out waiting for database lock")
+
+// LockOptions configures the database-level lock taken around schema changes
+type LockOptions struct {
+	Name           string        // Processes using the same name exclude each other; defaults to DefaultLockName
+	Timeout        time.Duration // How long to wait for another process; zero tries once
+	SkipWhenLocked bool          // Skip the work instead of failing when the lock can't be taken in time
+}
+
+// lockRunner is the part of *sql.Conn and *sql.Tx a lock needs; both keep every statement on one connection
+type lockRunner interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// Lock is a held database-level lock: pg_advisory_lock on PostgreSQL, GET_LOCK on MySQL
+type Lock struct {
+	name    string
+	session ISession
+	runner  lockRunner
+	conn    *sql.Conn // Reserved connection to return to the pool on Release, if any
+}
+
+// AcquireLock takes the named lock, waiting up to timeout for another process to release it.
+//
+// The lock belongs to a database connection, so a connection is reserved from the session's pool
+// for as long as the lock is held. Inside a transaction, the transaction's connection is used.
+// A pool of one connection is refused: the work done under the lock would wait forever for the
+// connection the lock is holding.
+func AcquireLock(session ISession, name string, timeout time.Duration) (*Lock, error) {
+	if name == "" {
+		name = DefaultLockName
+	}
+	ctx := sessionContext(session)
+
+	lock := &Lock{name: name, session: session}
+	switch s := unwrapSession(session).(type) {
+	case *TxSession:
+		lock.runner = s.Tx
+	case interface {
+		Conn(ctx context.Context) (*sql.Conn, error)
+		Stats() sql.DBStats
+	}:
+		if s.Stats().MaxOpenConnections == 1 {
+			return nil, fmt.Errorf("lock %s needs a connection of its own; allow the pool at least 2 connections", name)
+		}
+		conn, err := s.Conn(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("reserving connection for lock %s: %w", name, err)
+		}
+		lock.runner = conn
+		lock.conn = conn
+	default:
+		return nil, fmt.Errorf("session %T cannot hold a database lock", session)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		acquired, err := lock.try(ctx)
+		if err != nil {
+			lock.close()
+			return nil, err
+		}
+		if acquired {
+			return lock, nil
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			lock.close()
+			return nil, fmt.Errorf("lock %s: %w", name, ErrLockTimeout)
+		}
+		select {
+		case <-ctx.Done():
+			lock.close()
+			return nil, fmt.Errorf("waiting for lock %s: %w", name, ctx.Err())
+		case <-time.After(min(lockPollInterval, remaining)):
+		}
+	}
+}
+
+// try makes a single attempt at the lock
+func (l *Lock) try(ctx context.Context) (bool, error) {
+	query, args := l.session.Dialect().AdvisoryLock(l.name)
+
+	var acquired sql.NullBool
+	err := l.runner.QueryRowContext(ctx, query, args...).Scan(&acquired)
+	if err != nil {
+		return false, fmt.Errorf("acquiring lock %s: %w", l.name, err)
+	}
+	return acquired.Valid && acquired.Bool, nil
+}
+
+// Release gives the lock up and returns its connection to the pool
+func (l *Lock) Release() error {
+	defer l.close()
+
+	query, args := l.session.Dialect().AdvisoryUnlock(l.name)
+
+	var released sql.NullBool
+	err := l.runner.QueryRowContext(context.Background(), query, args...).Scan(&released)
+	if err != nil {
+		return fmt.Errorf("releasing lock %s: %w", l.name, err)
+	}
+	if !released.Valid || !released.Bool {
+		return fmt.Errorf("releasing lock %s: lock was not held", l.name)
+	}
+	return nil
+}
+
+func (l *Lock) close() {
+	if l.conn != nil {
+		_ = l.conn.Close()
+		l.conn = nil
+	}
+}
+
+// WithLock runs fn while holding the lock described by options. It reports whether fn ran:
+// when options.SkipWhenLocked is set and the lock stays taken past the timeout, fn is skipped
+// and no error is returned. The lock is released even if fn panics.
+func WithLock(session ISession, options LockOptions, fn func() error) (ran bool, err error) {
+	lock, err := AcquireLock(session, options.Name, options.Timeout)
+	if errors.Is(err, ErrLockTimeout) && options.SkipWhenLocked {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		err = errors.Join(err, lock.Release())
+	}()
+
+	return true, fn()
+}
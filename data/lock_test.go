@@ -0,0 +1,164 @@
+package data
+
+import (
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"gormless/data/dialect"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestWithLockPostgres(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	session := &Session{DB: db}
+	session.SQLDialect = dialect.PostgresDialect{}
+
+	// The first attempt finds the lock taken, the second gets it
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT pg_try_advisory_lock($1)")).
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"pg_try_advisory_lock"}).AddRow(false))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT pg_try_advisory_lock($1)")).
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"pg_try_advisory_lock"}).AddRow(true))
+	mock.ExpectExec("CREATE TABLE").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT pg_advisory_unlock($1)")).
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"pg_advisory_unlock"}).AddRow(true))
+
+	ran, err := WithLock(session, LockOptions{Timeout: time.Second}, func() error {
+		_, err := session.Exec("CREATE TABLE example (id INTEGER)")
+		return err
+	})
+
+	assert.NoError(t, err)
+	assert.True(t, ran)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWithLockTimeout(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	session := &Session{DB: db}
+	session.SQLDialect = dialect.MySQLDialect{}
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT GET_LOCK(?, 0)")).
+		WithArgs("deploy").
+		WillReturnRows(sqlmock.NewRows([]string{"GET_LOCK"}).AddRow(0))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT GET_LOCK(?, 0)")).
+		WithArgs("deploy").
+		WillReturnRows(sqlmock.NewRows([]string{"GET_LOCK"}).AddRow(0))
+
+	fn := func() error {
+		t.Error("fn must not run without the lock")
+		return nil
+	}
+
+	_, err = WithLock(session, LockOptions{Name: "deploy"}, fn)
+	assert.True(t, errors.Is(err, ErrLockTimeout))
+
+	ran, err := WithLock(session, LockOptions{Name: "deploy", SkipWhenLocked: true}, fn)
+	assert.NoError(t, err)
+	assert.False(t, ran)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrationRunnerSkipsWhenLocked(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	session := &Session{DB: db}
+	session.SQLDialect = dialect.PostgresDialect{}
+
+	registry := NewMigrationRegistry()
+	assert.NoError(t, registry.Register("0001", Table{Name: "user"}, func(table Table, session ISession) error {
+		t.Error("migration must not run without the lock")
+		return nil
+	}))
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT pg_try_advisory_lock($1)")).
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"pg_try_advisory_lock"}).AddRow(false))
+
+	runner := NewMigrationRunner(session, registry)
+	runner.Lock = &LockOptions{SkipWhenLocked: true}
+	report, err := runner.Migrate()
+
+	assert.NoError(t, err)
+	assert.True(t, report.LockBusy)
+	assert.Empty(t, report.Applied)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrationRunnerRefusesLockOnSingleConnection(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	session := &Session{DB: db}
+	session.SQLDialect = dialect.PostgresDialect{}
+
+	registry := NewMigrationRegistry()
+	assert.NoError(t, registry.Register("0001", Table{Name: "user"}, func(table Table, session ISession) error {
+		t.Error("migration must not run without the lock")
+		return nil
+	}))
+
+	runner := NewMigrationRunner(session, registry)
+	runner.Lock = &LockOptions{Timeout: time.Second}
+
+	// The lock would hold the only connection, leaving none for the migrations
+	done := make(chan error, 1)
+	go func() {
+		_, err := runner.Migrate()
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		assert.ErrorContains(t, err, "allow the pool at least 2 connections")
+	case <-time.After(5 * time.Second):
+		t.Fatal("Migrate deadlocked on a single connection")
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWithLockReleasesOnPanic(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	session := &Session{DB: db}
+	session.SQLDialect = dialect.PostgresDialect{}
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT pg_try_advisory_lock($1)")).
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"pg_try_advisory_lock"}).AddRow(true))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT pg_advisory_unlock($1)")).
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"pg_advisory_unlock"}).AddRow(true))
+
+	assert.PanicsWithValue(t, "boom", func() {
+		_, _ = WithLock(session, LockOptions{}, func() error { panic("boom") })
+	})
+	assert.NoError(t, mock.ExpectationsWereMet())
+	// The reserved connection went back to the pool
+	assert.Equal(t, 0, session.Stats().InUse)
+}
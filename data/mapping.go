@@ -0,0 +1,231 @@
+package data
+
+import (
+	"database/sql"
+	"fmt"
+	"iter"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// structField is a struct field mapped to a column
+type structField struct {
+	Column  string
+	Index   []int           // Field index path, through any embedded structs
+	Options map[string]bool // Options listed after the column name in the db tag
+}
+
+// structMapping maps the columns of a table onto the fields of a struct type
+type structMapping struct {
+	Fields   []structField
+	byColumn map[string]*structField
+}
+
+// structMappings caches a structMapping per struct type
+var structMappings sync.Map
+
+// mappingFor returns the column mapping for a struct type.
+//
+// Fields are matched to columns by their db tag, e.g. `db:"user_email"`, or by the snake_case
+// form of their name when they have none. A tag of "-" skips the field. Fields of embedded
+// structs are mapped as if they belonged to the outer struct.
+func mappingFor(t reflect.Type) (*structMapping, error) {
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("cannot map columns onto %s: not a struct", t)
+	}
+	if cached, ok := structMappings.Load(t); ok {
+		return cached.(*structMapping), nil
+	}
+
+	mapping := &structMapping{byColumn: make(map[string]*structField)}
+	collectFields(t, nil, mapping)
+	for i := range mapping.Fields {
+		mapping.byColumn[mapping.Fields[i].Column] = &mapping.Fields[i]
+	}
+
+	cached, _ := structMappings.LoadOrStore(t, mapping)
+	return cached.(*structMapping), nil
+}
+
+func collectFields(t reflect.Type, parent []int, mapping *structMapping) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		index := append(append([]int(nil), parent...), i)
+
+		tag, hasTag := field.Tag.Lookup("db")
+		if tag == "-" {
+			continue
+		}
+		if field.Anonymous && !hasTag && field.Type.Kind() == reflect.Struct {
+			collectFields(field.Type, index, mapping)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		parts := strings.Split(tag, ",")
+		column := parts[0]
+		if column == "" {
+			column = toSnakeCase(field.Name)
+		}
+		options := make(map[string]bool)
+		for _, option := range parts[1:] {
+			options[strings.TrimSpace(option)] = true
+		}
+
+		mapping.Fields = append(mapping.Fields, structField{Column: column, Index: index, Options: options})
+	}
+}
+
+// toSnakeCase converts a Go field name to snake_case, keeping acronyms together: UserID becomes user_id
+func toSnakeCase(name string) string {
+	runes := []rune(name)
+	var builder strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			previousLower := i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]))
+			acronymEnds := i > 0 && unicode.IsUpper(runes[i-1]) && i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if previousLower || acronymEnds {
+				builder.WriteByte('_')
+			}
+			builder.WriteRune(unicode.ToLower(r))
+			continue
+		}
+		builder.WriteRune(r)
+	}
+	return builder.String()
+}
+
+// ScanRows reads every remaining row into a T and closes rows.
+//
+// T is either a struct, whose fields are matched to the result columns as described on DAO, or a
+// map[string]any keyed by column name. Columns without a matching field are ignored. NULLs
+// need a sql.Null* or pointer field to land in.
+func ScanRows[T any](rows *sql.Rows) ([]T, error) {
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	var results []T
+	for rows.Next() {
+		var item T
+		err = scanInto(rows, columns, &item)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// ScanOne reads the first row into a T and closes rows. If there is none, it returns an error
+// matching both ErrNotFound and sql.ErrNoRows.
+func ScanOne[T any](rows *sql.Rows) (T, error) {
+	defer rows.Close()
+
+	var item T
+	columns, err := rows.Columns()
+	if err != nil {
+		return item, err
+	}
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return item, err
+		}
+		return item, errNoRows
+	}
+
+	err = scanInto(rows, columns, &item)
+	return item, err
+}
+
+// EachRow iterates over rows, scanning each into a T as ScanRows does. Rows are closed when the
+// loop ends, including on break. A scan error or a failed read is yielded once and ends the loop.
+//
+// Rows can only be read once, so neither can the sequence; use SelectQuery.Each for one that
+// reruns its query every time it's ranged over.
+func EachRow[T any](rows *sql.Rows) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		defer rows.Close()
+
+		var zero T
+		columns, err := rows.Columns()
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+
+		for rows.Next() {
+			var item T
+			if err := scanInto(rows, columns, &item); err != nil {
+				yield(zero, err)
+				return
+			}
+			if !yield(item, nil) {
+				return
+			}
+		}
+		if err := rows.Err(); err != nil {
+			yield(zero, err)
+		}
+	}
+}
+
+// scanInto scans the current row into dest, a pointer to a struct or a map[string]any
+func scanInto(rows *sql.Rows, columns []string, dest any) error {
+	value := reflect.ValueOf(dest).Elem()
+
+	if value.Kind() == reflect.Map {
+		if value.Type().Key().Kind() != reflect.String || value.Type().Elem().Kind() != reflect.Interface {
+			return fmt.Errorf("cannot scan into %s: only map[string]any is supported", value.Type())
+		}
+		values := make([]any, len(columns))
+		targets := make([]any, len(columns))
+		for i := range values {
+			targets[i] = &values[i]
+		}
+		if err := rows.Scan(targets...); err != nil {
+			return err
+		}
+
+		if value.IsNil() {
+			value.Set(reflect.MakeMapWithSize(value.Type(), len(columns)))
+		}
+		for i, column := range columns {
+			cell := reflect.ValueOf(values[i])
+			if !cell.IsValid() {
+				cell = reflect.Zero(value.Type().Elem())
+			}
+			value.SetMapIndex(reflect.ValueOf(column), cell)
+		}
+		return nil
+	}
+
+	mapping, err := mappingFor(value.Type())
+	if err != nil {
+		return err
+	}
+
+	targets := make([]any, len(columns))
+	for i, column := range columns {
+		field, ok := mapping.byColumn[column]
+		if !ok {
+			targets[i] = new(any) // Discard columns the struct doesn't have
+			continue
+		}
+		targets[i] = value.FieldByIndex(field.Index).Addr().Interface()
+	}
+
+	return rows.Scan(targets...)
+}
@@ -0,0 +1,92 @@
+package data
+
+import (
+	"database/sql"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestToSnakeCase(t *testing.T) {
+	tests := map[string]string{
+		"FirstName":  "first_name",
+		"UserID":     "user_id",
+		"ID":         "id",
+		"HTTPServer": "http_server",
+		"Address2":   "address2",
+		"email":      "email",
+	}
+	for name, expected := range tests {
+		assert.Equal(t, expected, toSnakeCase(name), name)
+	}
+}
+
+type auditFields struct {
+	CreatedBy string
+}
+
+type mappedUser struct {
+	auditFields
+	ID        int    `db:"user_id"`
+	FirstName string `db:"user_first"`
+	Nickname  *string
+	Phone     sql.NullString
+	Internal  string `db:"-"`
+	ignored   string
+}
+
+func TestScanRows(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT").WillReturnRows(
+		sqlmock.NewRows([]string{"user_id", "user_first", "nickname", "phone", "created_by", "not_mapped"}).
+			AddRow(1, "John", "Johnny", "555-0100", "admin", "x").
+			AddRow(2, "Jane", nil, nil, "admin", "y"))
+
+	rows, err := db.Query("SELECT")
+	assert.NoError(t, err)
+
+	users, err := ScanRows[mappedUser](rows)
+
+	assert.NoError(t, err)
+	if assert.Len(t, users, 2) {
+		assert.Equal(t, 1, users[0].ID)
+		assert.Equal(t, "John", users[0].FirstName)
+		assert.Equal(t, "Johnny", *users[0].Nickname)
+		assert.Equal(t, sql.NullString{String: "555-0100", Valid: true}, users[0].Phone)
+		assert.Equal(t, "admin", users[0].CreatedBy)
+
+		assert.Nil(t, users[1].Nickname)
+		assert.False(t, users[1].Phone.Valid)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestScanOneIntoMap(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT").WillReturnRows(
+		sqlmock.NewRows([]string{"role_id", "role_name"}).AddRow(1, "admin"))
+	mock.ExpectQuery("SELECT").WillReturnRows(
+		sqlmock.NewRows([]string{"role_id", "role_name"}))
+
+	rows, err := db.Query("SELECT")
+	assert.NoError(t, err)
+	role, err := ScanOne[map[string]any](rows)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"role_id": int64(1), "role_name": "admin"}, role)
+
+	rows, err = db.Query("SELECT")
+	assert.NoError(t, err)
+	_, err = ScanOne[map[string]any](rows)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
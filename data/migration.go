@@ -0,0 +1,613 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"gormless/data/dialect"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// versionTable is the name of the table that records which migrations have been applied.
+const versionTable = "version"
+
+// maxVersionLength matches the width of the database_version column created by InitDatabaseVersion.
+const maxVersionLength = 32
+
+// checksumLength is the length of a hex-encoded SHA-256 migration checksum
+const checksumLength = 64
+
+// ErrChecksumMismatch is returned when an applied migration no longer generates the SQL it was applied with
+var ErrChecksumMismatch = errors.New("migration checksum mismatch")
+
+// AppliedVersion is a row of the version table
+type AppliedVersion struct {
+	Version  string
+	Date     time.Time
+	Checksum string // Empty for versions recorded before checksums were kept
+}
+
+// VersionedMigration is a Migration registered under the version ID it brings the database to.
+//
+// A migration's checksum is taken from the statements it runs through the session it is given
+// when it is applied. To check an applied migration for drift, the runner calls it once more,
+// against a recording session, so migrations should only touch the database through that session.
+type VersionedMigration struct {
+	Version   string
+	Table     Table
+	Migration Migration
+	Rollback  Migration // Undoes Migration; nil if the migration is forward-only
+}
+
+// MigrationRegistry collects migrations by version ID.
+//
+// Version IDs are applied in lexical order, so they should sort the way they were written,
+// e.g. zero-padded sequence numbers ("0001_create_user") or timestamps ("20250101120000_add_status").
+type MigrationRegistry struct {
+	migrations map[string]VersionedMigration
+
+	mu        sync.Mutex
+	checksums map[checksumKey]checksumResult // Applied migrations' checksums, taken once per dialect
+}
+
+// checksumKey identifies a migration's checksum for one dialect
+type checksumKey struct {
+	dialect string
+	version string
+}
+
+type checksumResult struct {
+	checksum string
+	err      error
+}
+
+// NewMigrationRegistry creates an empty registry
+func NewMigrationRegistry() *MigrationRegistry {
+	return &MigrationRegistry{
+		migrations: make(map[string]VersionedMigration),
+		checksums:  make(map[checksumKey]checksumResult),
+	}
+}
+
+// checksum returns the migration's checksum for the given dialect, running it against a recording
+// session the first time it is asked for
+func (r *MigrationRegistry) checksum(d dialect.Dialect, migration VersionedMigration) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := checksumKey{dialect: fmt.Sprintf("%T", d), version: migration.Version}
+	result, ok := r.checksums[key]
+	if !ok {
+		result.checksum, result.err = MigrationChecksum(d, migration.Table, migration.Migration)
+		r.checksums[key] = result
+	}
+	return result.checksum, result.err
+}
+
+// Register adds a migration for the given table under a version ID.
+// Registering the same version ID twice is an error.
+func (r *MigrationRegistry) Register(version string, table Table, migration Migration) error {
+	if version == "" {
+		return fmt.Errorf("migration version must not be empty")
+	}
+	if len(version) > maxVersionLength {
+		return fmt.Errorf("migration version %q is longer than %d characters", version, maxVersionLength)
+	}
+	if migration == nil {
+		return fmt.Errorf("migration %s has no migration function", version)
+	}
+	if _, exists := r.migrations[version]; exists {
+		return fmt.Errorf("migration %s is already registered", version)
+	}
+
+	r.migrations[version] = VersionedMigration{
+		Version:   version,
+		Table:     table,
+		Migration: migration,
+	}
+	return nil
+}
+
+// RegisterReversible adds a migration that can be rolled back under a version ID
+func (r *MigrationRegistry) RegisterReversible(version string, table Table, migration ReversibleMigration) error {
+	if migration.Down == nil {
+		return fmt.Errorf("migration %s has no down migration", version)
+	}
+	err := r.Register(version, table, migration.Up)
+	if err != nil {
+		return err
+	}
+
+	registered := r.migrations[version]
+	registered.Rollback = migration.Down
+	r.migrations[version] = registered
+	return nil
+}
+
+// Migrations returns every registered migration in the order it should be applied
+func (r *MigrationRegistry) Migrations() []VersionedMigration {
+	migrations := make([]VersionedMigration, 0, len(r.migrations))
+	for _, migration := range r.migrations {
+		migrations = append(migrations, migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations
+}
+
+// TransactionMode controls how a MigrationRunner wraps migrations in transactions
+type TransactionMode int
+
+const (
+	// TransactionPerMigration runs each migration and its version table record in its own transaction.
+	TransactionPerMigration TransactionMode = iota
+	// TransactionPerBatch runs every pending migration and its version table record in one transaction.
+	TransactionPerBatch
+	// Checkpoint runs migrations without a transaction, recording each version as soon as its
+	// migration succeeds. A failing migration may leave some of its changes behind.
+	// This is the only mode available on dialects without transactional DDL, such as MySQL.
+	Checkpoint
+)
+
+func (m TransactionMode) String() string {
+	switch m {
+	case TransactionPerMigration:
+		return "transaction per migration"
+	case TransactionPerBatch:
+		return "transaction per batch"
+	case Checkpoint:
+		return "checkpoint"
+	default:
+		return fmt.Sprintf("TransactionMode(%d)", int(m))
+	}
+}
+
+// DriftPolicy controls what a MigrationRunner does when an applied migration's checksum has changed
+type DriftPolicy int
+
+const (
+	// DriftFail refuses to apply anything while any applied migration has drifted
+	DriftFail DriftPolicy = iota
+	// DriftWarn logs drifted migrations, lists them in the report, and carries on
+	DriftWarn
+)
+
+// ChecksumDrift describes an applied migration whose code no longer matches what was applied
+type ChecksumDrift struct {
+	Version  string
+	Recorded string // Checksum stored in the version table
+	Current  string // Checksum of the SQL the registered migration generates now
+	Err      error  // Why Current couldn't be found, if it couldn't
+}
+
+func (d ChecksumDrift) String() string {
+	if d.Err != nil {
+		return fmt.Sprintf("migration %s can't be checked for changes since it was applied: %v", d.Version, d.Err)
+	}
+	return fmt.Sprintf("migration %s has changed since it was applied (recorded %s, now %s)",
+		d.Version, d.Recorded, d.Current)
+}
+
+// MigrationChecksum returns the SHA-256 of the statements migration generates for the given dialect,
+// found by running it against a recording session. It is the checksum the runner records when it
+// applies the migration, unless the migration's SQL depends on what the database returns.
+func MigrationChecksum(d dialect.Dialect, table Table, migration Migration) (string, error) {
+	session, _ := newRecordingSession(d)
+	defer session.Close()
+
+	capture := newCapturingSession(session)
+	err := migration(table, capture)
+	if err != nil {
+		return "", err
+	}
+	return capture.checksum(), nil
+}
+
+// MigrationReport describes what a MigrationRunner did
+type MigrationReport struct {
+	Applied    []string        // Versions applied by this run, in order
+	RolledBack []string        // Versions rolled back by this run, in order
+	Skipped    []string        // Versions that were already recorded in the version table
+	Current    string          // The latest version recorded once the run finished
+	Mode       TransactionMode // The transaction mode the run actually used
+	LockBusy   bool            // Another process held the lock, so the run was skipped
+	Drifted    []ChecksumDrift // Applied migrations whose checksum no longer matches the code, or can't be found
+}
+
+// MigrationError reports the migration a run stopped at
+type MigrationError struct {
+	Version string
+	Action  string // "applying" or "rolling back"
+	Partial bool   // The migration ran outside a transaction and may have left changes behind
+	Err     error
+}
+
+func (e *MigrationError) Error() string {
+	msg := fmt.Sprintf("%s migration %s: %v", e.Action, e.Version, e.Err)
+	if e.Partial {
+		msg += "; it ran outside a transaction and may be partially applied"
+	}
+	return msg
+}
+
+func (e *MigrationError) Unwrap() error {
+	return e.Err
+}
+
+// MigrationRunner applies the pending migrations of a registry and records them in the version table.
+type MigrationRunner struct {
+	ISession
+	Registry *MigrationRegistry
+	Mode     TransactionMode // Defaults to TransactionPerMigration
+	Lock     *LockOptions    // If set, runs hold a database-level lock so concurrent deploys don't race
+	OnDrift  DriftPolicy     // Defaults to DriftFail
+}
+
+// NewMigrationRunner creates a runner for the given session and registry
+func NewMigrationRunner(session ISession, registry *MigrationRegistry) *MigrationRunner {
+	return &MigrationRunner{ISession: session, Registry: registry}
+}
+
+// effectiveMode is the requested Mode, downgraded to Checkpoint when the dialect would
+// implicitly commit DDL statements and make a transaction meaningless.
+func (r *MigrationRunner) effectiveMode() TransactionMode {
+	if !r.Dialect().TransactionalDDL() {
+		return Checkpoint
+	}
+	return r.Mode
+}
+
+// AppliedVersions reads the version table and returns its rows keyed by version ID.
+func (r *MigrationRunner) AppliedVersions() (map[string]AppliedVersion, error) {
+	query := r.Dialect().Sprintd(
+		"SELECT %i, %i, %i FROM %i",
+		"database_version",
+		"version_date",
+		"checksum",
+		versionTable)
+
+	rows, err := r.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("reading version table: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[string]AppliedVersion)
+	for rows.Next() {
+		var version AppliedVersion
+		var checksum sql.NullString
+		if err := rows.Scan(&version.Version, &version.Date, &checksum); err != nil {
+			return nil, fmt.Errorf("reading version table: %w", err)
+		}
+		// CHAR columns come back padded with spaces
+		version.Version = strings.TrimSpace(version.Version)
+		version.Checksum = strings.TrimSpace(checksum.String)
+		applied[version.Version] = version
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("reading version table: %w", err)
+	}
+
+	return applied, nil
+}
+
+// Pending returns the registered migrations that are not yet recorded in the version table
+func (r *MigrationRunner) Pending() ([]VersionedMigration, error) {
+	applied, err := r.AppliedVersions()
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []VersionedMigration
+	for _, migration := range r.Registry.Migrations() {
+		if _, ok := applied[migration.Version]; !ok {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// Migrate applies every pending migration in version order, recording each one in the version table
+// together with its changes. It stops at the first failure; the report lists what was applied before it.
+func (r *MigrationRunner) Migrate() (*MigrationReport, error) {
+	report := &MigrationReport{Mode: r.effectiveMode()}
+	err := r.locked(report, func() error {
+		return r.migrate(report)
+	})
+	return report, err
+}
+
+// MigrateContext runs Migrate under ctx: the version table, the lock and every migration use a
+// session bound to ctx, so cancelling it stops the run at the statement in flight.
+func (r *MigrationRunner) MigrateContext(ctx context.Context) (*MigrationReport, error) {
+	return r.withContext(ctx).Migrate()
+}
+
+func (r *MigrationRunner) migrate(report *MigrationReport) error {
+	applied, err := r.AppliedVersions()
+	if err != nil {
+		return err
+	}
+	return r.migrateFrom(report, applied)
+}
+
+// migrateFrom applies the migrations missing from applied
+func (r *MigrationRunner) migrateFrom(report *MigrationReport, applied map[string]AppliedVersion) error {
+	for version := range applied {
+		if version > report.Current {
+			report.Current = version
+		}
+	}
+
+	var steps []migrationStep
+	for _, migration := range r.Registry.Migrations() {
+		version, ok := applied[migration.Version]
+		if !ok {
+			steps = append(steps, migrationStep{
+				VersionedMigration: migration,
+				action:             "applying",
+				run:                migration.Migration,
+				record: func(session ISession, checksum string) error {
+					return UpsertDbVersion(session, migration.Version, checksum)
+				},
+			})
+			continue
+		}
+
+		// Versions recorded before checksums were kept have nothing to compare against
+		report.Skipped = append(report.Skipped, migration.Version)
+		if version.Checksum == "" {
+			continue
+		}
+		checksum, err := r.Registry.checksum(r.Dialect(), migration)
+		if err != nil || checksum != version.Checksum {
+			report.Drifted = append(report.Drifted, ChecksumDrift{
+				Version:  migration.Version,
+				Recorded: version.Checksum,
+				Current:  checksum,
+				Err:      err,
+			})
+		}
+	}
+
+	for _, drift := range report.Drifted {
+		if r.OnDrift == DriftFail {
+			if drift.Err != nil {
+				return fmt.Errorf("migration %s can't be checked for changes since it was applied: %w",
+					drift.Version, drift.Err)
+			}
+			return fmt.Errorf("%s: %w", drift, ErrChecksumMismatch)
+		}
+		log.Printf("warning: %s", drift)
+	}
+
+	return r.runSteps(report.Mode, steps, func(version string) {
+		report.Applied = append(report.Applied, version)
+		if version > report.Current {
+			report.Current = version
+		}
+	})
+}
+
+// Rollback undoes every applied migration newer than target, newest first, removing each one from
+// the version table together with its changes. An empty target rolls back everything. Nothing is run
+// unless every migration to be undone is registered with a down migration.
+func (r *MigrationRunner) Rollback(target string) (*MigrationReport, error) {
+	report := &MigrationReport{Mode: r.effectiveMode()}
+	err := r.locked(report, func() error {
+		return r.rollback(report, target)
+	})
+	return report, err
+}
+
+// RollbackContext runs Rollback under ctx, as MigrateContext does for Migrate
+func (r *MigrationRunner) RollbackContext(ctx context.Context, target string) (*MigrationReport, error) {
+	return r.withContext(ctx).Rollback(target)
+}
+
+// withContext returns a copy of the runner whose session is bound to ctx
+func (r *MigrationRunner) withContext(ctx context.Context) *MigrationRunner {
+	bound := *r
+	bound.ISession = WithContext(ctx, r.ISession)
+	return &bound
+}
+
+func (r *MigrationRunner) rollback(report *MigrationReport, target string) error {
+	err := r.checkTarget(target)
+	if err != nil {
+		return err
+	}
+
+	applied, err := r.AppliedVersions()
+	if err != nil {
+		return err
+	}
+	return r.rollbackFrom(report, applied, target)
+}
+
+// checkTarget makes sure a rollback target is either empty or a registered version
+func (r *MigrationRunner) checkTarget(target string) error {
+	if target == "" {
+		return nil
+	}
+	if _, ok := r.Registry.migrations[target]; !ok {
+		return fmt.Errorf("unknown target version %s", target)
+	}
+	return nil
+}
+
+// rollbackFrom undoes the migrations in applied that are newer than target
+func (r *MigrationRunner) rollbackFrom(report *MigrationReport, applied map[string]AppliedVersion, target string) error {
+	registered := make(map[string]VersionedMigration)
+	for _, migration := range r.Registry.Migrations() {
+		registered[migration.Version] = migration
+	}
+
+	var steps []migrationStep
+	for version := range applied {
+		if version <= target {
+			if version > report.Current {
+				report.Current = version
+			}
+			continue
+		}
+		migration, ok := registered[version]
+		if !ok {
+			return fmt.Errorf("applied migration %s is not registered", version)
+		}
+		if migration.Rollback == nil {
+			return fmt.Errorf("migration %s cannot be rolled back", version)
+		}
+		steps = append(steps, migrationStep{
+			VersionedMigration: migration,
+			action:             "rolling back",
+			run:                migration.Rollback,
+			record: func(session ISession, checksum string) error {
+				return DeleteDbVersion(session, migration.Version)
+			},
+		})
+	}
+	sort.Slice(steps, func(i, j int) bool {
+		return steps[i].Version > steps[j].Version
+	})
+
+	return r.runSteps(report.Mode, steps, func(version string) {
+		report.RolledBack = append(report.RolledBack, version)
+	})
+}
+
+// Plan returns the statements Migrate would run, including the version table updates, without
+// running them. The version table is read to find the pending migrations; nothing is written.
+func (r *MigrationRunner) Plan() (Plan, error) {
+	applied, err := r.AppliedVersions()
+	if err != nil {
+		return nil, err
+	}
+
+	planner, dryRun := r.planner()
+	defer dryRun.Close()
+	err = planner.migrateFrom(&MigrationReport{Mode: r.effectiveMode()}, applied)
+	return dryRun.Plan(), err
+}
+
+// PlanRollback returns the statements Rollback would run for target, without running them.
+func (r *MigrationRunner) PlanRollback(target string) (Plan, error) {
+	err := r.checkTarget(target)
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := r.AppliedVersions()
+	if err != nil {
+		return nil, err
+	}
+
+	planner, dryRun := r.planner()
+	defer dryRun.Close()
+	err = planner.rollbackFrom(&MigrationReport{Mode: r.effectiveMode()}, applied, target)
+	return dryRun.Plan(), err
+}
+
+// planner returns a copy of the runner that sends its statements to a dry-run session, which the
+// caller closes
+func (r *MigrationRunner) planner() (*MigrationRunner, *DryRunSession) {
+	dryRun := NewDryRunSession(r.Dialect())
+	planner := *r
+	planner.ISession = dryRun
+	planner.Lock = nil
+	return &planner, dryRun
+}
+
+// locked runs fn under the runner's lock, if it has one
+func (r *MigrationRunner) locked(report *MigrationReport, fn func() error) error {
+	if r.Lock == nil {
+		return fn()
+	}
+
+	ran, err := WithLock(r.ISession, *r.Lock, fn)
+	report.LockBusy = !ran && err == nil
+	return err
+}
+
+// migrationStep is one migration, run in one direction, plus the version table change that records it
+type migrationStep struct {
+	VersionedMigration
+	action string
+	run    Migration
+	record func(session ISession, checksum string) error // checksum is that of the SQL run
+}
+
+// apply runs the step and records it on the given session
+func (s migrationStep) apply(session ISession) error {
+	capture := newCapturingSession(session)
+	err := s.run(s.Table, capture)
+	if err != nil {
+		return err
+	}
+
+	err = s.record(session, capture.checksum())
+	if err != nil {
+		return fmt.Errorf("updating version table: %w", err)
+	}
+	return nil
+}
+
+// runSteps applies steps in order using the given transaction mode, calling done for each step
+// once its changes are durable.
+func (r *MigrationRunner) runSteps(mode TransactionMode, steps []migrationStep, done func(version string)) error {
+	if len(steps) == 0 {
+		return nil
+	}
+
+	switch mode {
+	case TransactionPerBatch:
+		var failed *MigrationError
+		err := inTransaction(r.ISession, func(tx ISession) error {
+			for _, step := range steps {
+				if err := step.apply(tx); err != nil {
+					failed = &MigrationError{Version: step.Version, Action: step.action, Err: err}
+					return failed
+				}
+			}
+			return nil
+		})
+		if failed != nil {
+			return failed
+		}
+		if err != nil {
+			return fmt.Errorf("migration batch: %w", err)
+		}
+		for _, step := range steps {
+			done(step.Version)
+		}
+
+	case TransactionPerMigration:
+		for _, step := range steps {
+			err := inTransaction(r.ISession, step.apply)
+			if err != nil {
+				return &MigrationError{Version: step.Version, Action: step.action, Err: err}
+			}
+			done(step.Version)
+		}
+
+	case Checkpoint:
+		for _, step := range steps {
+			err := step.apply(r.ISession)
+			if err != nil {
+				return &MigrationError{Version: step.Version, Action: step.action, Partial: true, Err: err}
+			}
+			done(step.Version)
+		}
+
+	default:
+		return fmt.Errorf("unknown transaction mode %s", mode)
+	}
+
+	return nil
+}
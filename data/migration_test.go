@@ -0,0 +1,406 @@
+package data
+
+import (
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"gormless/data/dialect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMigrationRegistryRegister(t *testing.T) {
+	registry := NewMigrationRegistry()
+	noop := func(table Table, session ISession) error { return nil }
+
+	assert.NoError(t, registry.Register("0002_second", Table{Name: "user"}, noop))
+	assert.NoError(t, registry.Register("0001_first", Table{Name: "user"}, noop))
+
+	err := registry.Register("0001_first", Table{Name: "user"}, noop)
+	assert.ErrorContains(t, err, "already registered")
+
+	err = registry.Register("", Table{Name: "user"}, noop)
+	assert.ErrorContains(t, err, "must not be empty")
+
+	err = registry.Register("0003_this_version_id_is_far_too_long", Table{Name: "user"}, noop)
+	assert.ErrorContains(t, err, "longer than 32")
+
+	migrations := registry.Migrations()
+	assert.Len(t, migrations, 2)
+	assert.Equal(t, "0001_first", migrations[0].Version)
+	assert.Equal(t, "0002_second", migrations[1].Version)
+}
+
+func TestMigrationRunnerMigrate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	session := &Session{DB: db}
+	session.SQLDialect = dialect.PostgresDialect{}
+
+	var ran []string
+	migration := func(name string) Migration {
+		return func(table Table, session ISession) error {
+			ran = append(ran, name)
+			_, err := session.Exec("ALTER TABLE " + table.Name + " " + name)
+			return err
+		}
+	}
+
+	registry := NewMigrationRegistry()
+	assert.NoError(t, registry.Register("0001", Table{Name: "user"}, migration("first")))
+	assert.NoError(t, registry.Register("0002", Table{Name: "user"}, migration("second")))
+	assert.NoError(t, registry.Register("0003", Table{Name: "user"}, migration("third")))
+
+	// 0001 is already applied; CHAR padding must not confuse the runner
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT \"database_version\", \"version_date\", \"checksum\" FROM \"version\"")).
+		WillReturnRows(sqlmock.NewRows([]string{"database_version", "version_date", "checksum"}).
+			AddRow("0001                            ", time.Now(), nil))
+
+	upsertSQL := regexp.QuoteMeta("INSERT INTO \"version\" (\"checksum\", \"database_version\", \"version_date\") VALUES ($1, $2, $3) ON CONFLICT (\"database_version\") DO UPDATE SET \"checksum\" = EXCLUDED.\"checksum\", \"version_date\" = EXCLUDED.\"version_date\"")
+	mock.ExpectBegin()
+	mock.ExpectExec("ALTER TABLE user second").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(upsertSQL).WithArgs(sqlmock.AnyArg(), "0002", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec("ALTER TABLE user third").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(upsertSQL).WithArgs(sqlmock.AnyArg(), "0003", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	report, err := NewMigrationRunner(session, registry).Migrate()
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"second", "third"}, ran)
+	assert.Equal(t, []string{"0002", "0003"}, report.Applied)
+	assert.Equal(t, []string{"0001"}, report.Skipped)
+	assert.Equal(t, "0003", report.Current)
+	assert.Equal(t, TransactionPerMigration, report.Mode)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrationRunnerStopsOnFailure(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	session := &Session{DB: db}
+	session.SQLDialect = dialect.PostgresDialect{}
+
+	registry := NewMigrationRegistry()
+	assert.NoError(t, registry.Register("0001", Table{Name: "user"}, func(table Table, session ISession) error {
+		return errors.New("boom")
+	}))
+	assert.NoError(t, registry.Register("0002", Table{Name: "user"}, func(table Table, session ISession) error {
+		t.Error("migration after a failure must not run")
+		return nil
+	}))
+
+	mock.ExpectQuery("SELECT").
+		WillReturnRows(sqlmock.NewRows([]string{"database_version", "version_date", "checksum"}))
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	report, err := NewMigrationRunner(session, registry).Migrate()
+
+	assert.ErrorContains(t, err, "applying migration 0001: boom")
+	var migrationErr *MigrationError
+	assert.ErrorAs(t, err, &migrationErr)
+	assert.False(t, migrationErr.Partial)
+	assert.Empty(t, report.Applied)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrationRunnerRollback(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	session := &Session{DB: db}
+	session.SQLDialect = dialect.PostgresDialect{}
+
+	statusType := dialect.PsqlText
+	nicknameType := dialect.PsqlText
+	users := Table{Name: "user"}
+
+	registry := NewMigrationRegistry()
+	assert.NoError(t, registry.RegisterReversible("0001", users, ReversibleAddColumn(users, Column{Name: "status", Type: &statusType})))
+	assert.NoError(t, registry.RegisterReversible("0002", users, ReversibleAddColumn(users, Column{Name: "nickname", Type: &nicknameType})))
+	assert.NoError(t, registry.RegisterReversible("0003", users, ReversibleRemoveColumn(users, Column{Name: "nickname", Type: &nicknameType})))
+
+	mock.ExpectQuery("SELECT").
+		WillReturnRows(sqlmock.NewRows([]string{"database_version", "version_date", "checksum"}).
+			AddRow("0001", time.Now(), nil).
+			AddRow("0002", time.Now(), nil).
+			AddRow("0003", time.Now(), nil))
+
+	deleteSQL := regexp.QuoteMeta("DELETE FROM \"version\" WHERE \"database_version\" = $1")
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("ALTER TABLE \"user\" ADD COLUMN \"nickname\" TEXT")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(deleteSQL).WithArgs("0003").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("ALTER TABLE \"user\" DROP COLUMN \"nickname\"")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(deleteSQL).WithArgs("0002").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	report, err := NewMigrationRunner(session, registry).Rollback("0001")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"0003", "0002"}, report.RolledBack)
+	assert.Equal(t, "0001", report.Current)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrationRunnerRollbackRequiresDown(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	session := &Session{DB: db}
+	session.SQLDialect = dialect.PostgresDialect{}
+
+	registry := NewMigrationRegistry()
+	assert.NoError(t, registry.Register("0001", Table{Name: "user"}, func(table Table, session ISession) error {
+		return nil
+	}))
+
+	mock.ExpectQuery("SELECT").
+		WillReturnRows(sqlmock.NewRows([]string{"database_version", "version_date", "checksum"}).
+			AddRow("0001", time.Now(), nil))
+
+	_, err = NewMigrationRunner(session, registry).Rollback("")
+	assert.ErrorContains(t, err, "migration 0001 cannot be rolled back")
+
+	_, err = NewMigrationRunner(session, registry).Rollback("0009")
+	assert.ErrorContains(t, err, "unknown target version 0009")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrationRunnerBatchRollsBackEverything(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	session := &Session{DB: db}
+	session.SQLDialect = dialect.PostgresDialect{}
+
+	registry := NewMigrationRegistry()
+	assert.NoError(t, registry.Register("0001", Table{Name: "user"}, func(table Table, session ISession) error {
+		_, err := session.Exec("ALTER TABLE user first")
+		return err
+	}))
+	assert.NoError(t, registry.Register("0002", Table{Name: "user"}, func(table Table, session ISession) error {
+		_, err := session.Exec("ALTER TABLE user second")
+		return err
+	}))
+
+	mock.ExpectQuery("SELECT").
+		WillReturnRows(sqlmock.NewRows([]string{"database_version", "version_date", "checksum"}))
+	mock.ExpectBegin()
+	mock.ExpectExec("ALTER TABLE user first").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO").WithArgs(sqlmock.AnyArg(), "0001", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("ALTER TABLE user second").WillReturnError(errors.New("boom"))
+	mock.ExpectRollback()
+
+	runner := NewMigrationRunner(session, registry)
+	runner.Mode = TransactionPerBatch
+	report, err := runner.Migrate()
+
+	assert.ErrorContains(t, err, "applying migration 0002")
+	assert.Empty(t, report.Applied, "nothing in a failed batch is applied")
+	assert.Equal(t, TransactionPerBatch, report.Mode)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrationRunnerCheckpointsOnMySQL(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	session := &Session{DB: db}
+	session.SQLDialect = dialect.MySQLDialect{}
+
+	registry := NewMigrationRegistry()
+	assert.NoError(t, registry.Register("0001", Table{Name: "user"}, func(table Table, session ISession) error {
+		_, err := session.Exec("ALTER TABLE user first")
+		return err
+	}))
+	assert.NoError(t, registry.Register("0002", Table{Name: "user"}, func(table Table, session ISession) error {
+		_, err := session.Exec("ALTER TABLE user second")
+		return err
+	}))
+
+	// No BEGIN/COMMIT: each migration is recorded as soon as it finishes
+	mock.ExpectQuery("SELECT").
+		WillReturnRows(sqlmock.NewRows([]string{"database_version", "version_date", "checksum"}))
+	mock.ExpectExec("ALTER TABLE user first").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO").WithArgs(sqlmock.AnyArg(), "0001", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("ALTER TABLE user second").WillReturnError(errors.New("boom"))
+
+	runner := NewMigrationRunner(session, registry)
+	runner.Mode = TransactionPerBatch
+	report, err := runner.Migrate()
+
+	var migrationErr *MigrationError
+	assert.ErrorAs(t, err, &migrationErr)
+	assert.Equal(t, "0002", migrationErr.Version)
+	assert.True(t, migrationErr.Partial)
+	assert.ErrorContains(t, err, "may be partially applied")
+	assert.Equal(t, []string{"0001"}, report.Applied)
+	assert.Equal(t, "0001", report.Current)
+	assert.Equal(t, Checkpoint, report.Mode)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrationRunnerDetectsDrift(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	session := &Session{DB: db}
+	session.SQLDialect = dialect.PostgresDialect{}
+
+	statusType := dialect.PsqlText
+	users := Table{Name: "user"}
+	original := AddColumn(users, Column{Name: "status", Type: &statusType})
+	recorded, err := MigrationChecksum(session.Dialect(), users, original)
+	assert.NoError(t, err)
+	assert.Len(t, recorded, checksumLength)
+
+	// Someone has since changed the column type in the migration's definition
+	editedType := dialect.PsqlInt
+	edited := AddColumn(users, Column{Name: "status", Type: &editedType})
+
+	registry := NewMigrationRegistry()
+	assert.NoError(t, registry.Register("0001", users, edited))
+	assert.NoError(t, registry.Register("0002", users, func(table Table, session ISession) error {
+		_, err := session.Exec("ALTER TABLE user second")
+		return err
+	}))
+
+	versions := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"database_version", "version_date", "checksum"}).
+			AddRow("0001", time.Now(), recorded)
+	}
+
+	// DriftFail refuses to apply 0002
+	mock.ExpectQuery("SELECT").WillReturnRows(versions())
+	report, err := NewMigrationRunner(session, registry).Migrate()
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+	assert.ErrorContains(t, err, "migration 0001 has changed")
+	assert.Empty(t, report.Applied)
+
+	// DriftWarn reports the drift and carries on
+	mock.ExpectQuery("SELECT").WillReturnRows(versions())
+	mock.ExpectBegin()
+	mock.ExpectExec("ALTER TABLE user second").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO").WithArgs(sqlmock.AnyArg(), "0002", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	runner := NewMigrationRunner(session, registry)
+	runner.OnDrift = DriftWarn
+	report, err = runner.Migrate()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"0002"}, report.Applied)
+	if assert.Len(t, report.Drifted, 1) {
+		assert.Equal(t, "0001", report.Drifted[0].Version)
+		assert.Equal(t, recorded, report.Drifted[0].Recorded)
+		assert.NotEqual(t, recorded, report.Drifted[0].Current)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrationRunnerRecordsChecksumOfAppliedSQL(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	session := &Session{DB: db}
+	session.SQLDialect = dialect.PostgresDialect{}
+
+	statusType := dialect.PsqlText
+	users := Table{Name: "user"}
+	addStatus := AddColumn(users, Column{Name: "status", Type: &statusType})
+	checksum, err := MigrationChecksum(session.Dialect(), users, addStatus)
+	assert.NoError(t, err)
+
+	runs := 0
+	registry := NewMigrationRegistry()
+	assert.NoError(t, registry.Register("0001", users, func(table Table, session ISession) error {
+		runs++
+		return addStatus(table, session)
+	}))
+
+	mock.ExpectQuery("SELECT").
+		WillReturnRows(sqlmock.NewRows([]string{"database_version", "version_date", "checksum"}))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("ALTER TABLE \"user\" ADD COLUMN \"status\" TEXT")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO").WithArgs(checksum, "0001", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	_, err = NewMigrationRunner(session, registry).Migrate()
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, runs, "the checksum comes from the run itself")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrationRunnerReportsUncheckableMigrations(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	session := &Session{DB: db}
+	session.SQLDialect = dialect.PostgresDialect{}
+
+	// Reads a row that a recording session never returns
+	runs := 0
+	registry := NewMigrationRegistry()
+	assert.NoError(t, registry.Register("0001", Table{Name: "user"}, func(table Table, session ISession) error {
+		runs++
+		var count int
+		return session.QueryRow("SELECT count(*) FROM \"user\"").Scan(&count)
+	}))
+
+	versions := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"database_version", "version_date", "checksum"}).
+			AddRow("0001", time.Now(), strings.Repeat("a", checksumLength))
+	}
+
+	mock.ExpectQuery("SELECT").WillReturnRows(versions())
+	_, err = NewMigrationRunner(session, registry).Migrate()
+	assert.ErrorContains(t, err, "migration 0001 can't be checked for changes")
+
+	mock.ExpectQuery("SELECT").WillReturnRows(versions())
+	runner := NewMigrationRunner(session, registry)
+	runner.OnDrift = DriftWarn
+	report, err := runner.Migrate()
+	assert.NoError(t, err)
+	if assert.Len(t, report.Drifted, 1) {
+		assert.Error(t, report.Drifted[0].Err)
+	}
+
+	assert.Equal(t, 1, runs, "an applied migration is checksummed once per dialect")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
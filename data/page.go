@@ -0,0 +1,321 @@
+package data
+
+import (
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"time"
+)
+
+// ErrInvalidCursor is returned for a cursor token that is malformed or was issued for a different ordering
+var ErrInvalidCursor = errors.New("invalid page cursor")
+
+// PageRequest asks for one page of a keyset-paginated listing
+type PageRequest struct {
+	Column string // Key to page on: the primary key, or an indexed column. Defaults to the primary key.
+	Desc   bool   // Page from the highest key down
+	Size   int    // Rows per page
+	Cursor string // Page.Next or Page.Prev from an earlier page; empty for the first page
+}
+
+// Page is one page of a listing, with cursors for the pages either side of it
+type Page[T any] struct {
+	Items []T
+	Next  string // Cursor for the following page; empty on the last page
+	Prev  string // Cursor for the preceding page; empty on the first page
+}
+
+// cursor is the decoded form of a cursor token
+type cursor struct {
+	Columns  []string      `json:"c"`
+	Values   []cursorValue `json:"v"`
+	Desc     bool          `json:"d,omitempty"`
+	Backward bool          `json:"b,omitempty"`
+
+	keys []any // Values, decoded to the types they were read as
+}
+
+// cursorValue is a key value in a cursor token, tagged with its type so it decodes to the type it
+// was read as: JSON alone would turn a []byte or time.Time key into a string
+type cursorValue struct {
+	Type  string          `json:"t"`
+	Value json.RawMessage `json:"v"`
+}
+
+// Page returns one page of the DAO's table. See SelectQuery.Page.
+func (dao *DAO[T]) Page(request PageRequest) (Page[T], error) {
+	return dao.Select().Page(request)
+}
+
+// Page runs the query one page at a time, using keyset pagination rather than OFFSET: each page
+// picks up after the last key of the one before, so a page deep into a large table costs the same
+// as the first.
+//
+// Rows are ordered by request.Column, with the primary key breaking ties when the column isn't
+// unique. The query must not have its own ORDER BY, LIMIT or OFFSET.
+//
+// E.g.,
+//
+//	page, err := dao.Select().Where(data.Eq("user_role", 2)).Page(data.PageRequest{Size: 50})
+//	...
+//	page, err = dao.Select().Where(data.Eq("user_role", 2)).Page(data.PageRequest{Size: 50, Cursor: page.Next})
+func (q *SelectQuery[T]) Page(request PageRequest) (Page[T], error) {
+	var page Page[T]
+	if request.Size <= 0 {
+		return page, fmt.Errorf("page size must be positive, got %d", request.Size)
+	}
+	if len(q.orderBy) > 0 || q.limit >= 0 || q.offset >= 0 {
+		return page, errors.New("cannot paginate a query with its own ORDER BY, LIMIT or OFFSET")
+	}
+
+	keys, err := q.dao.pageKeys(request.Column)
+	if err != nil {
+		return page, err
+	}
+
+	var after *cursor
+	if request.Cursor != "" {
+		after, err = decodeCursor(request.Cursor)
+		if err != nil {
+			return page, err
+		}
+		if !slices.Equal(after.Columns, keys) || after.Desc != request.Desc || len(after.keys) != len(keys) {
+			return page, fmt.Errorf("%w: it was issued for a different ordering", ErrInvalidCursor)
+		}
+	}
+	backward := after != nil && after.Backward
+
+	// Walking backward flips the order; the page is put the right way round once it's read
+	descending := request.Desc != backward
+
+	pageQuery := *q
+	pageQuery.where = slices.Clone(q.where)
+	pageQuery.orderBy = nil
+	qualified := make([]string, len(keys))
+	for i, key := range keys {
+		qualified[i] = q.dao.Table.Name + "." + key
+		pageQuery.orderBy = append(pageQuery.orderBy, ordering{column: qualified[i], desc: descending})
+	}
+	if after != nil {
+		pageQuery.where = append(pageQuery.where, keysetCondition(qualified, after.keys, descending))
+	}
+	pageQuery.limit = request.Size + 1 // One extra row tells us whether there's another page
+
+	items, err := pageQuery.All()
+	if err != nil {
+		return page, err
+	}
+	more := len(items) > request.Size
+	if more {
+		items = items[:request.Size]
+	}
+	if backward {
+		slices.Reverse(items)
+	}
+	page.Items = items
+	if len(items) == 0 {
+		return page, nil
+	}
+
+	// Going forward there is a previous page if we came from a cursor, and a next one if the extra
+	// row came back; going backward it's the other way round
+	hasNext, hasPrev := more, after != nil
+	if backward {
+		hasNext, hasPrev = true, more
+	}
+	if hasNext {
+		page.Next, err = encodeCursor(items[len(items)-1], keys, request.Desc, false)
+		if err != nil {
+			return page, err
+		}
+	}
+	if hasPrev {
+		page.Prev, err = encodeCursor(items[0], keys, request.Desc, true)
+		if err != nil {
+			return page, err
+		}
+	}
+	return page, nil
+}
+
+// pageKeys returns the columns to order a page by: column, then the primary key columns to break ties.
+//
+// Keys must never be NULL: a row whose key is NULL can't be compared with the rows either side of
+// it, so Page reports an error rather than a cursor past it.
+func (dao *DAO[T]) pageKeys(column string) ([]string, error) {
+	var primaryKey []string
+	var found *Column
+	for _, c := range tableColumns(dao.Table) {
+		if c.PrimaryKey {
+			primaryKey = append(primaryKey, c.Name)
+		}
+		if c.Name == column {
+			found = &c
+		}
+	}
+	if len(primaryKey) == 0 {
+		return nil, fmt.Errorf("cannot paginate %s: it has no primary key", dao.Table.Name)
+	}
+	if column == "" {
+		return primaryKey, nil
+	}
+	if found == nil || !(found.PrimaryKey || found.Indexed) {
+		return nil, fmt.Errorf("cannot paginate %s on %s: it is not the primary key or an indexed column",
+			dao.Table.Name, column)
+	}
+
+	keys := []string{column}
+	for _, key := range primaryKey {
+		if key != column {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// keysetCondition matches rows ordered after values: (k1, k2) > (v1, v2), written out so both
+// dialects can use the index
+func keysetCondition(keys []string, values []any, descending bool) Condition {
+	past := Gt
+	if descending {
+		past = Lt
+	}
+	alternatives := make([]Condition, len(keys))
+	for i := range keys {
+		terms := make([]Condition, 0, i+1)
+		for j := 0; j < i; j++ {
+			terms = append(terms, Eq(keys[j], values[j]))
+		}
+		terms = append(terms, past(keys[i], values[i]))
+		alternatives[i] = And(terms...)
+	}
+	return Or(alternatives...)
+}
+
+func encodeCursor(item any, keys []string, desc bool, backward bool) (string, error) {
+	values := make([]cursorValue, len(keys))
+	for i, key := range keys {
+		value, err := columnValue(item, key)
+		if err != nil {
+			return "", err
+		}
+		values[i], err = encodeCursorValue(key, value)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	encoded, err := json.Marshal(cursor{Columns: keys, Values: values, Desc: desc, Backward: backward})
+	if err != nil {
+		return "", fmt.Errorf("encoding page cursor: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(encoded), nil
+}
+
+// encodeCursorValue tags a key's value with its type, once it is converted to a driver value
+func encodeCursorValue(key string, value any) (cursorValue, error) {
+	value, err := driver.DefaultParameterConverter.ConvertValue(value)
+	if err != nil {
+		return cursorValue{}, fmt.Errorf("encoding page key %s: %w", key, err)
+	}
+
+	var tag string
+	switch value.(type) {
+	case nil:
+		return cursorValue{}, fmt.Errorf("cannot page past a row whose %s is NULL; page on a column that is never NULL", key)
+	case int64:
+		tag = "int"
+	case float64:
+		tag = "float"
+	case bool:
+		tag = "bool"
+	case string:
+		tag = "string"
+	case []byte:
+		tag = "bytes"
+	case time.Time:
+		tag = "time"
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return cursorValue{}, fmt.Errorf("encoding page key %s: %w", key, err)
+	}
+	return cursorValue{Type: tag, Value: encoded}, nil
+}
+
+func decodeCursor(token string) (*cursor, error) {
+	encoded, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c cursor
+	if err := json.Unmarshal(encoded, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	c.keys = make([]any, len(c.Values))
+	for i, value := range c.Values {
+		c.keys[i], err = value.decode()
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+	}
+	return &c, nil
+}
+
+// decode returns the value as the type it was tagged with
+func (v cursorValue) decode() (any, error) {
+	switch v.Type {
+	case "int":
+		return decodeAs[int64](v.Value)
+	case "float":
+		return decodeAs[float64](v.Value)
+	case "bool":
+		return decodeAs[bool](v.Value)
+	case "string":
+		return decodeAs[string](v.Value)
+	case "bytes":
+		return decodeAs[[]byte](v.Value)
+	case "time":
+		return decodeAs[time.Time](v.Value)
+	default:
+		return nil, fmt.Errorf("unknown page key type %q", v.Type)
+	}
+}
+
+func decodeAs[V any](raw json.RawMessage) (any, error) {
+	var value V
+	err := json.Unmarshal(raw, &value)
+	return value, err
+}
+
+// columnValue reads a column's value out of a scanned row, a struct or a map[string]any
+func columnValue(item any, column string) (any, error) {
+	value := reflect.ValueOf(item)
+	var field reflect.Value
+	switch value.Kind() {
+	case reflect.Map:
+		field = value.MapIndex(reflect.ValueOf(column))
+	case reflect.Struct:
+		mapping, err := mappingFor(value.Type())
+		if err != nil {
+			return nil, err
+		}
+		if mapped, ok := mapping.byColumn[column]; ok {
+			field = value.FieldByIndex(mapped.Index)
+		}
+	}
+	if !field.IsValid() {
+		return nil, fmt.Errorf("cannot read page key %s from %T", column, item)
+	}
+
+	if valuer, ok := field.Interface().(driver.Valuer); ok {
+		return valuer.Value()
+	}
+	return field.Interface(), nil
+}
@@ -0,0 +1,204 @@
+package data
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"gormless/data/dialect"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func userRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"user_id", "user_first", "user_email"})
+}
+
+func TestDAOPage(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	session := &Session{DB: db}
+	session.SQLDialect = dialect.PostgresDialect{}
+	dao := DAO[testUser]{ISession: session, Table: testUserTable()}
+
+	// First page: the extra row shows there's a next page
+	mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT * FROM "user" ORDER BY "user"."user_id" LIMIT 3`)).
+		WillReturnRows(userRows().AddRow(1, "Ann", "a@x").AddRow(2, "Bob", "b@x").AddRow(3, "Cy", "c@x"))
+
+	first, err := dao.Page(PageRequest{Size: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2}, userIDs(first.Items))
+	assert.Empty(t, first.Prev)
+	assert.NotEmpty(t, first.Next)
+
+	// Second page picks up after the last key and is the last page
+	mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT * FROM "user" WHERE "user"."user_id" > $1 ORDER BY "user"."user_id" LIMIT 3`)).
+		WithArgs(2).
+		WillReturnRows(userRows().AddRow(3, "Cy", "c@x"))
+
+	second, err := dao.Page(PageRequest{Size: 2, Cursor: first.Next})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{3}, userIDs(second.Items))
+	assert.Empty(t, second.Next)
+	assert.NotEmpty(t, second.Prev)
+
+	// Going back walks the key downward and flips the rows round
+	mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT * FROM "user" WHERE "user"."user_id" < $1 ORDER BY "user"."user_id" DESC LIMIT 3`)).
+		WithArgs(3).
+		WillReturnRows(userRows().AddRow(2, "Bob", "b@x").AddRow(1, "Ann", "a@x"))
+
+	back, err := dao.Page(PageRequest{Size: 2, Cursor: second.Prev})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2}, userIDs(back.Items))
+	assert.Empty(t, back.Prev)
+	assert.NotEmpty(t, back.Next)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDAOPageOnIndexedColumn(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	session := &Session{DB: db}
+	session.SQLDialect = dialect.MySQLDialect{}
+	dao := DAO[testUser]{ISession: session, Table: testUserTable()}
+
+	mock.ExpectQuery(regexp.QuoteMeta(
+		"SELECT * FROM `user` WHERE `user_first` = ? ORDER BY `user`.`user_email` DESC, `user`.`user_id` DESC LIMIT 2")).
+		WithArgs("Bob").
+		WillReturnRows(userRows().AddRow(5, "Bob", "z@x").AddRow(4, "Bob", "y@x"))
+
+	first, err := dao.Select().Where(Eq("user_first", "Bob")).
+		Page(PageRequest{Column: "user_email", Desc: true, Size: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{5}, userIDs(first.Items))
+
+	// The primary key breaks ties between equal emails
+	mock.ExpectQuery(regexp.QuoteMeta(
+		"SELECT * FROM `user` WHERE (`user_first` = ? AND (`user`.`user_email` < ? OR (`user`.`user_email` = ? AND `user`.`user_id` < ?))) "+
+			"ORDER BY `user`.`user_email` DESC, `user`.`user_id` DESC LIMIT 2")).
+		WithArgs("Bob", "z@x", "z@x", 5).
+		WillReturnRows(userRows().AddRow(4, "Bob", "y@x"))
+
+	_, err = dao.Select().Where(Eq("user_first", "Bob")).
+		Page(PageRequest{Column: "user_email", Desc: true, Size: 1, Cursor: first.Next})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDAOPageRejects(t *testing.T) {
+	session, _ := newRecordingSession(dialect.PostgresDialect{})
+	dao := DAO[testUser]{ISession: session, Table: testUserTable()}
+
+	_, err := dao.Page(PageRequest{Column: "user_first", Size: 10})
+	assert.EqualError(t, err, "cannot paginate user on user_first: it is not the primary key or an indexed column")
+
+	_, err = dao.Page(PageRequest{Size: 10, Cursor: "not a cursor"})
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+
+	token, err := encodeCursor(testUser{ID: 1}, []string{"user_id"}, false, false)
+	assert.NoError(t, err)
+	_, err = dao.Page(PageRequest{Size: 10, Desc: true, Cursor: token})
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+
+	_, err = dao.Select().OrderBy("user_id").Page(PageRequest{Size: 10})
+	assert.Error(t, err)
+}
+
+type testEvent struct {
+	ID        int        `db:"event_id"`
+	CreatedAt time.Time  `db:"created_at"`
+	ClosedAt  *time.Time `db:"closed_at"`
+}
+
+func testEventTable() Table {
+	return Table{
+		Name: "event",
+		Columns: &[]Column{
+			{Name: "event_id", Type: stringPtr(dialect.PsqlSerial), PrimaryKey: true},
+			{Name: "created_at", Type: stringPtr("TIMESTAMP"), Indexed: true},
+			{Name: "closed_at", Type: stringPtr("TIMESTAMP"), Indexed: true},
+		},
+	}
+}
+
+func TestDAOPageOnTimestamp(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	session := &Session{DB: db}
+	session.SQLDialect = dialect.MySQLDialect{}
+	dao := DAO[testEvent]{ISession: session, Table: testEventTable()}
+
+	created := time.Date(2025, 3, 1, 12, 30, 0, 123456000, time.UTC)
+	mock.ExpectQuery(regexp.QuoteMeta(
+		"SELECT * FROM `event` ORDER BY `event`.`created_at`, `event`.`event_id` LIMIT 2")).
+		WillReturnRows(sqlmock.NewRows([]string{"event_id", "created_at"}).
+			AddRow(1, created).AddRow(2, created.Add(time.Hour)))
+
+	first, err := dao.Page(PageRequest{Column: "created_at", Size: 1})
+	assert.NoError(t, err)
+
+	// The key comes back from the cursor as a time, not the string JSON would make of it
+	mock.ExpectQuery(regexp.QuoteMeta(
+		"SELECT * FROM `event` WHERE (`event`.`created_at` > ? OR (`event`.`created_at` = ? AND `event`.`event_id` > ?)) "+
+			"ORDER BY `event`.`created_at`, `event`.`event_id` LIMIT 2")).
+		WithArgs(created, created, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"event_id", "created_at"}).AddRow(2, created.Add(time.Hour)))
+
+	_, err = dao.Page(PageRequest{Column: "created_at", Size: 1, Cursor: first.Next})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDAOPageRefusesNullKey(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	session := &Session{DB: db}
+	session.SQLDialect = dialect.PostgresDialect{}
+	dao := DAO[testEvent]{ISession: session, Table: testEventTable()}
+
+	mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT * FROM "event" ORDER BY "event"."closed_at", "event"."event_id" LIMIT 2`)).
+		WillReturnRows(sqlmock.NewRows([]string{"event_id", "closed_at"}).AddRow(1, nil).AddRow(2, nil))
+
+	_, err = dao.Page(PageRequest{Column: "closed_at", Size: 1})
+	assert.ErrorContains(t, err, "cannot page past a row whose closed_at is NULL")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCursorKeepsKeyTypes(t *testing.T) {
+	created := time.Date(2025, 3, 1, 12, 30, 0, 0, time.UTC)
+	row := map[string]any{"token": []byte{0xde, 0xad}, "created_at": created, "id": int64(1) << 60}
+
+	token, err := encodeCursor(row, []string{"token", "created_at", "id"}, false, false)
+	assert.NoError(t, err)
+	decoded, err := decodeCursor(token)
+	assert.NoError(t, err)
+	assert.Equal(t, []any{[]byte{0xde, 0xad}, created, int64(1) << 60}, decoded.keys)
+}
+
+func userIDs(users []testUser) []int {
+	ids := make([]int, len(users))
+	for i, user := range users {
+		ids[i] = user.ID
+	}
+	return ids
+}
@@ -0,0 +1,92 @@
+package data
+
+import (
+	"fmt"
+	"gormless/data/dialect"
+	"io"
+	"strings"
+	"time"
+)
+
+// DryRunSession is an ISession that collects the statements sent to it instead of running them.
+// Pass it to CreateTable, a Migration or a DAO to see the SQL they would issue.
+//
+// Executions report zero rows affected and queries return no rows, so code that branches on what
+// the database returns only shows the path taken against an empty database.
+//
+// E.g.,
+//
+//	dryRun := data.NewDryRunSession(dialect.PostgresDialect{})
+//	defer dryRun.Close()
+//	err := data.CreateTable(dryRun, tables.UserTable()())
+//	fmt.Print(dryRun.Plan())
+type DryRunSession struct {
+	*Session
+	rec *recorder
+}
+
+// NewDryRunSession creates a dry-run session that renders SQL for the given dialect
+func NewDryRunSession(d dialect.Dialect) *DryRunSession {
+	session, rec := newRecordingSession(d)
+	return &DryRunSession{Session: session, rec: rec}
+}
+
+// Open is a no-op; a dry run never connects to a database
+func (s *DryRunSession) Open(dsn string) error {
+	return nil
+}
+
+// Plan returns the statements collected so far, in the order they were issued
+func (s *DryRunSession) Plan() Plan {
+	return Plan(s.rec.Statements())
+}
+
+// Plan is an ordered list of statements, as collected by a DryRunSession
+type Plan []Statement
+
+// String renders the plan as a SQL script
+func (p Plan) String() string {
+	var builder strings.Builder
+	_, _ = p.WriteTo(&builder)
+	return builder.String()
+}
+
+// WriteTo writes the plan as a SQL script, one statement per line. Bound arguments are listed in a
+// comment above the statement they belong to, so the script can be reviewed or saved as a .sql file.
+func (p Plan) WriteTo(w io.Writer) (int64, error) {
+	var written int64
+	for _, statement := range p {
+		var builder strings.Builder
+		if len(statement.Args) > 0 {
+			args := make([]string, len(statement.Args))
+			for i, arg := range statement.Args {
+				args[i] = fmt.Sprintf("%d = %s", i+1, formatPlanArg(arg))
+			}
+			builder.WriteString("-- args: " + strings.Join(args, ", ") + "\n")
+		}
+		builder.WriteString(strings.TrimSuffix(strings.TrimSpace(statement.SQL), ";") + ";\n")
+
+		n, err := io.WriteString(w, builder.String())
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// formatPlanArg renders a bound argument as a SQL literal for display
+func formatPlanArg(arg any) string {
+	switch v := arg.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return "'" + strings.ReplaceAll(v, "'", "''") + "'"
+	case []byte:
+		return fmt.Sprintf("'\\x%x'", v)
+	case time.Time:
+		return "'" + v.Format(time.RFC3339Nano) + "'"
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
@@ -0,0 +1,179 @@
+package data
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"gormless/data/dialect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDryRunSessionCollectsStatements(t *testing.T) {
+	dryRun := NewDryRunSession(dialect.PostgresDialect{})
+
+	idType := dialect.PsqlSmallSerial
+	nameType := "VARCHAR(32)"
+	table := Table{
+		Name: "user_role",
+		Columns: &[]Column{
+			{Name: "role_id", Type: &idType, PrimaryKey: true},
+			{Name: "role_name", Type: &nameType},
+		},
+	}
+
+	err := CreateTable(dryRun, table)
+	assert.NoError(t, err)
+
+	dao := DAO[any]{ISession: dryRun, Table: table}
+	err = dao.Upsert(map[string]any{"role_name": "admin"}, map[string]any{"role_name": "o'brien"})
+	assert.NoError(t, err)
+
+	plan := dryRun.Plan()
+	if assert.Len(t, plan, 2) {
+		assert.Equal(t, "CREATE TABLE IF NOT EXISTS \"user_role\" (\"role_id\" SMALLSERIAL PRIMARY KEY, \"role_name\" VARCHAR(32));", plan[0].SQL)
+		assert.Equal(t, []any{"admin", "o'brien"}, plan[1].Args)
+	}
+
+	assert.Equal(t,
+		"CREATE TABLE IF NOT EXISTS \"user_role\" (\"role_id\" SMALLSERIAL PRIMARY KEY, \"role_name\" VARCHAR(32));\n"+
+			"-- args: 1 = 'admin', 2 = 'o''brien'\n"+
+			"INSERT INTO \"user_role\" (\"role_name\") VALUES ($1), ($2) ON CONFLICT (\"role_id\") DO UPDATE SET \"role_name\" = EXCLUDED.\"role_name\";\n",
+		plan.String())
+}
+
+func TestMigrationRunnerPlan(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	session := &Session{DB: db}
+	session.SQLDialect = dialect.PostgresDialect{}
+
+	statusType := dialect.PsqlText
+	users := Table{Name: "user"}
+	registry := NewMigrationRegistry()
+	assert.NoError(t, registry.RegisterReversible("0001", users, ReversibleAddColumn(users, Column{Name: "status", Type: &statusType})))
+	assert.NoError(t, registry.RegisterReversible("0002", users, ReversibleModifyColumn(users, Column{Name: "status"}, Column{Name: "state"})))
+
+	// Only the version table is read
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT \"database_version\", \"version_date\", \"checksum\" FROM \"version\"")).
+		WillReturnRows(sqlmock.NewRows([]string{"database_version", "version_date", "checksum"}).
+			AddRow("0001", time.Now(), nil))
+
+	plan, err := NewMigrationRunner(session, registry).Plan()
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+	sql := make([]string, len(plan))
+	for i, statement := range plan {
+		sql[i] = statement.SQL
+	}
+	assert.Equal(t, []string{
+		"BEGIN",
+		"ALTER TABLE \"user\" RENAME COLUMN \"status\" TO \"state\"",
+		"INSERT INTO \"version\" (\"checksum\", \"database_version\", \"version_date\") VALUES ($1, $2, $3) ON CONFLICT (\"database_version\") DO UPDATE SET \"checksum\" = EXCLUDED.\"checksum\", \"version_date\" = EXCLUDED.\"version_date\"",
+		"COMMIT",
+	}, sql)
+	assert.Equal(t, "0002", plan[2].Args[1])
+}
+
+func TestMigrationRunnerPlanFollowsTransactionMode(t *testing.T) {
+	statusType := dialect.PsqlText
+	users := Table{Name: "user"}
+	registry := NewMigrationRegistry()
+	assert.NoError(t, registry.Register("0001", users, AddColumn(users, Column{Name: "status", Type: &statusType})))
+	assert.NoError(t, registry.Register("0002", users, AddColumn(users, Column{Name: "state", Type: &statusType})))
+
+	tests := []struct {
+		name     string
+		dialect  dialect.Dialect
+		mode     TransactionMode
+		expected []string
+	}{
+		{
+			name:     "per migration",
+			dialect:  dialect.PostgresDialect{},
+			mode:     TransactionPerMigration,
+			expected: []string{"BEGIN", "ALTER", "INSERT", "COMMIT", "BEGIN", "ALTER", "INSERT", "COMMIT"},
+		},
+		{
+			name:     "per batch",
+			dialect:  dialect.PostgresDialect{},
+			mode:     TransactionPerBatch,
+			expected: []string{"BEGIN", "ALTER", "INSERT", "ALTER", "INSERT", "COMMIT"},
+		},
+		{
+			name:     "checkpoint",
+			dialect:  dialect.PostgresDialect{},
+			mode:     Checkpoint,
+			expected: []string{"ALTER", "INSERT", "ALTER", "INSERT"},
+		},
+		{
+			// DDL commits implicitly on MySQL, so the runner checkpoints whatever mode is asked for
+			name:     "checkpoint on MySQL",
+			dialect:  dialect.MySQLDialect{},
+			mode:     TransactionPerMigration,
+			expected: []string{"ALTER", "INSERT", "ALTER", "INSERT"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("Error creating mock database: %v", err)
+			}
+			defer db.Close()
+
+			session := &Session{DB: db}
+			session.SQLDialect = tt.dialect
+			mock.ExpectQuery("SELECT").
+				WillReturnRows(sqlmock.NewRows([]string{"database_version", "version_date", "checksum"}))
+
+			runner := NewMigrationRunner(session, registry)
+			runner.Mode = tt.mode
+			plan, err := runner.Plan()
+
+			assert.NoError(t, err)
+			verbs := make([]string, len(plan))
+			for i, statement := range plan {
+				verbs[i] = strings.Fields(statement.SQL)[0]
+			}
+			assert.Equal(t, tt.expected, verbs)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestMigrationRunnerPlanRollbackFollowsTransactionMode(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	session := &Session{DB: db}
+	session.SQLDialect = dialect.MySQLDialect{}
+
+	statusType := dialect.MySqlText
+	users := Table{Name: "user"}
+	registry := NewMigrationRegistry()
+	assert.NoError(t, registry.RegisterReversible("0001", users, ReversibleAddColumn(users, Column{Name: "status", Type: &statusType})))
+
+	mock.ExpectQuery("SELECT").
+		WillReturnRows(sqlmock.NewRows([]string{"database_version", "version_date", "checksum"}).
+			AddRow("0001", time.Now(), nil))
+
+	plan, err := NewMigrationRunner(session, registry).PlanRollback("")
+
+	assert.NoError(t, err)
+	if assert.Len(t, plan, 2) {
+		assert.Equal(t, "ALTER TABLE `user` DROP COLUMN `status`", plan[0].SQL)
+		assert.Equal(t, "DELETE FROM `version` WHERE `database_version` = ?", plan[1].SQL)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
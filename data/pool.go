@@ -0,0 +1,79 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"gormless/data/dialect"
+	"time"
+)
+
+// open returns a pool of connections from connector, sized and set up as p says
+func (p Pool) open(connector driver.Connector, d dialect.Dialect) *sql.DB {
+	pooled := &poolConnector{Connector: connector, timeout: time.Duration(p.ConnectionTimeout)}
+	if d != nil {
+		pooled.settings = d.TimeoutSettings(time.Duration(p.StatementTimeout), time.Duration(p.LockTimeout))
+	}
+
+	db := sql.OpenDB(pooled)
+	if p.MaxConnections > 0 {
+		db.SetMaxOpenConns(p.MaxConnections)
+	}
+	if p.MaxIdleConnections > 0 {
+		db.SetMaxIdleConns(p.MaxIdleConnections)
+	}
+	if p.IdleTimeout > 0 {
+		db.SetConnMaxIdleTime(time.Duration(p.IdleTimeout))
+	}
+	if p.MaxLifetime > 0 {
+		db.SetConnMaxLifetime(time.Duration(p.MaxLifetime))
+	}
+	return db
+}
+
+// poolConnector opens connections for a pool, giving up after timeout and running the pool's
+// session settings, e.g. its statement timeout, on each new connection
+type poolConnector struct {
+	driver.Connector
+	timeout  time.Duration
+	settings []string
+}
+
+func (c *poolConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, setting := range c.settings {
+		if err := execSetting(ctx, conn, setting); err != nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("setting up connection with %q: %w", setting, err)
+		}
+	}
+	return conn, nil
+}
+
+// execSetting runs a statement without arguments directly on a driver connection
+func execSetting(ctx context.Context, conn driver.Conn, setting string) error {
+	if execer, ok := conn.(driver.ExecerContext); ok {
+		_, err := execer.ExecContext(ctx, setting, nil)
+		if err != driver.ErrSkip {
+			return err
+		}
+	}
+
+	stmt, err := conn.Prepare(setting)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	_, err = stmt.Exec(nil)
+	return err
+}
@@ -0,0 +1,118 @@
+package data
+
+import (
+	"context"
+	"database/sql/driver"
+	"github.com/stretchr/testify/assert"
+	"gormless/data/dialect"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadConfigReadsPool(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db_config.yml")
+	err := os.WriteFile(path, []byte(`database:
+  host: localhost
+  dbname: gotest
+  connection_timeout: 30
+  idle_timeout: 10
+  max_connections: 20
+  statement_timeout: 2.5s
+  lock_timeout: 0.5
+`), 0o600)
+	assert.NoError(t, err)
+
+	conf, err := LoadConfig(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "gotest", conf.Database.DBName)
+	assert.Equal(t, Pool{
+		MaxConnections:    20,
+		IdleTimeout:       Duration(10 * time.Second),
+		ConnectionTimeout: Duration(30 * time.Second),
+		StatementTimeout:  Duration(2500 * time.Millisecond),
+		LockTimeout:       Duration(500 * time.Millisecond),
+	}, conf.Database.Pool)
+
+	err = os.WriteFile(path, []byte("database:\n  idle_timeout: soon\n"), 0o600)
+	assert.NoError(t, err)
+	_, err = LoadConfig(path)
+	assert.ErrorContains(t, err, "neither a number of seconds nor a duration")
+}
+
+func TestPoolSetsUpConnections(t *testing.T) {
+	pool := Pool{
+		MaxConnections:   5,
+		StatementTimeout: Duration(2 * time.Second),
+		LockTimeout:      Duration(time.Second),
+	}
+	tests := []struct {
+		name     string
+		dialect  dialect.Dialect
+		settings []string
+	}{
+		{
+			name:     "postgres",
+			dialect:  dialect.PostgresDialect{},
+			settings: []string{"SET statement_timeout = 2000", "SET lock_timeout = 1000"},
+		},
+		{
+			name:     "mysql",
+			dialect:  dialect.MySQLDialect{},
+			settings: []string{"SET SESSION max_execution_time = 2000", "SET SESSION innodb_lock_wait_timeout = 1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := &recorder{}
+			session := &Session{DB: pool.open(rec, tt.dialect), SQLDialect: tt.dialect}
+			defer session.Close()
+
+			_, err := session.Exec("DELETE FROM user")
+			assert.NoError(t, err)
+
+			// The settings run once, when the connection is opened, ahead of anything sent through it
+			_, err = session.Exec("DELETE FROM user_role")
+			assert.NoError(t, err)
+			statements := rec.Statements()
+			if assert.Len(t, statements, 4) {
+				assert.Equal(t, tt.settings[0], statements[0].SQL)
+				assert.Equal(t, tt.settings[1], statements[1].SQL)
+				assert.Equal(t, "DELETE FROM user", statements[2].SQL)
+			}
+
+			stats := session.Stats()
+			assert.Equal(t, 5, stats.MaxOpenConnections)
+			assert.Equal(t, 1, stats.OpenConnections)
+		})
+	}
+}
+
+func TestOpenUsesTheDialectsDriver(t *testing.T) {
+	// A lib/pq DSN means nothing to go-sql-driver/mysql, so a MySQL session fails before dialing
+	session := &Session{SQLDialect: dialect.MySQLDialect{}}
+	err := session.Open("user=app dbname=app sslmode=disable")
+	assert.ErrorContains(t, err, "invalid DSN")
+	assert.Nil(t, session.DB)
+}
+
+// stalledConnector never manages to connect, as if the server were unreachable
+type stalledConnector struct {
+	recorder
+}
+
+func (c *stalledConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestPoolConnectionTimeout(t *testing.T) {
+	pool := Pool{ConnectionTimeout: Duration(10 * time.Millisecond)}
+	db := pool.open(&stalledConnector{}, dialect.PostgresDialect{})
+	defer db.Close()
+
+	err := db.Ping()
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
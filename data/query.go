@@ -0,0 +1,453 @@
+package data
+
+import (
+	"database/sql"
+	"fmt"
+	"gormless/data/dialect"
+	"iter"
+	"strings"
+)
+
+// Condition is a predicate in a WHERE clause. Build conditions with Eq, In, Between, IsNull and
+// friends, and combine them with And and Or.
+type Condition interface {
+	writeSQL(w *sqlWriter)
+}
+
+// sqlWriter accumulates a statement and the arguments bound to it
+type sqlWriter struct {
+	dialect dialect.Dialect
+	builder strings.Builder
+	args    []any
+	table   string   // Qualifies unqualified column names, if set
+	columns []string // Every column written, unqualified
+}
+
+func (w *sqlWriter) WriteString(s string) {
+	w.builder.WriteString(s)
+}
+
+// bind adds value to the arguments and writes its placeholder. An Inserted value is written as a
+// reference to the incoming row instead.
+func (w *sqlWriter) bind(value any) {
+	if column, ok := value.(inserted); ok {
+		w.builder.WriteString(w.dialect.Inserted(string(column)))
+		return
+	}
+	w.args = append(w.args, value)
+	w.builder.WriteString(w.dialect.Placeholder(len(w.args)))
+}
+
+// column writes a quoted column name. Qualified names, e.g. "user_role.role_name", are quoted part by part.
+func (w *sqlWriter) column(name string) {
+	w.columns = append(w.columns, name[strings.LastIndex(name, ".")+1:])
+	if w.table != "" && !strings.Contains(name, ".") {
+		name = w.table + "." + name
+	}
+	w.builder.WriteString(quoteQualified(w.dialect, name))
+}
+
+// inserted is the value an upsert tried to insert into a column
+type inserted string
+
+// Inserted stands for the value an upsert tried to insert into column, for comparing against the
+// existing row in UpsertOptions.Where
+func Inserted(column string) any {
+	return inserted(column)
+}
+
+func quoteQualified(d dialect.Dialect, name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		if part != "*" {
+			parts[i] = d.QuoteIdentifier(part)
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+type comparison struct {
+	column   string
+	operator string
+	value    any
+}
+
+func (c comparison) writeSQL(w *sqlWriter) {
+	w.column(c.column)
+	w.WriteString(" " + c.operator + " ")
+	w.bind(c.value)
+}
+
+// Eq matches rows where column = value
+func Eq(column string, value any) Condition { return comparison{column, "=", value} }
+
+// NotEq matches rows where column <> value
+func NotEq(column string, value any) Condition { return comparison{column, "<>", value} }
+
+// Lt matches rows where column < value
+func Lt(column string, value any) Condition { return comparison{column, "<", value} }
+
+// LtEq matches rows where column <= value
+func LtEq(column string, value any) Condition { return comparison{column, "<=", value} }
+
+// Gt matches rows where column > value
+func Gt(column string, value any) Condition { return comparison{column, ">", value} }
+
+// GtEq matches rows where column >= value
+func GtEq(column string, value any) Condition { return comparison{column, ">=", value} }
+
+// Like matches rows where column LIKE pattern
+func Like(column string, pattern string) Condition { return comparison{column, "LIKE", pattern} }
+
+type inList struct {
+	column string
+	values []any
+	not    bool
+}
+
+func (c inList) writeSQL(w *sqlWriter) {
+	if len(c.values) == 0 {
+		// Nothing is in an empty list; "IN ()" isn't valid SQL
+		if c.not {
+			w.WriteString("1 = 1")
+		} else {
+			w.WriteString("1 = 0")
+		}
+		return
+	}
+	w.column(c.column)
+	if c.not {
+		w.WriteString(" NOT")
+	}
+	w.WriteString(" IN (")
+	for i, value := range c.values {
+		if i > 0 {
+			w.WriteString(", ")
+		}
+		w.bind(value)
+	}
+	w.WriteString(")")
+}
+
+// In matches rows where column is one of values. An empty list matches nothing.
+func In(column string, values ...any) Condition { return inList{column: column, values: values} }
+
+// NotIn matches rows where column is none of values. An empty list matches everything.
+func NotIn(column string, values ...any) Condition {
+	return inList{column: column, values: values, not: true}
+}
+
+type between struct {
+	column    string
+	low, high any
+}
+
+func (c between) writeSQL(w *sqlWriter) {
+	w.column(c.column)
+	w.WriteString(" BETWEEN ")
+	w.bind(c.low)
+	w.WriteString(" AND ")
+	w.bind(c.high)
+}
+
+// Between matches rows where low <= column <= high
+func Between(column string, low, high any) Condition { return between{column, low, high} }
+
+type nullCheck struct {
+	column string
+	not    bool
+}
+
+func (c nullCheck) writeSQL(w *sqlWriter) {
+	w.column(c.column)
+	if c.not {
+		w.WriteString(" IS NOT NULL")
+	} else {
+		w.WriteString(" IS NULL")
+	}
+}
+
+// IsNull matches rows where column is NULL
+func IsNull(column string) Condition { return nullCheck{column: column} }
+
+// IsNotNull matches rows where column is not NULL
+func IsNotNull(column string) Condition { return nullCheck{column: column, not: true} }
+
+type group struct {
+	operator   string
+	conditions []Condition
+}
+
+func (g group) writeSQL(w *sqlWriter) {
+	if len(g.conditions) == 0 {
+		// An empty AND is true and an empty OR is false, as with the logic they stand for
+		if g.operator == "AND" {
+			w.WriteString("1 = 1")
+		} else {
+			w.WriteString("1 = 0")
+		}
+		return
+	}
+	if len(g.conditions) == 1 {
+		g.conditions[0].writeSQL(w)
+		return
+	}
+	w.WriteString("(")
+	for i, condition := range g.conditions {
+		if i > 0 {
+			w.WriteString(" " + g.operator + " ")
+		}
+		condition.writeSQL(w)
+	}
+	w.WriteString(")")
+}
+
+// And matches rows that meet every condition
+func And(conditions ...Condition) Condition { return group{"AND", conditions} }
+
+// Or matches rows that meet any of the conditions
+func Or(conditions ...Condition) Condition { return group{"OR", conditions} }
+
+type negation struct {
+	condition Condition
+}
+
+func (n negation) writeSQL(w *sqlWriter) {
+	w.WriteString("NOT (")
+	n.condition.writeSQL(w)
+	w.WriteString(")")
+}
+
+// Not matches rows that don't meet condition
+func Not(condition Condition) Condition { return negation{condition} }
+
+type join struct {
+	kind  string
+	table string
+	on    [2]string // Local and foreign column, qualified
+}
+
+type ordering struct {
+	column string
+	desc   bool
+}
+
+// SelectQuery builds a SELECT against a DAO's table and scans the results into T. Create one with
+// DAO.Select; every method returns the query so calls can be chained.
+//
+// E.g.,
+//
+//	admins, err := dao.Select().
+//		Join("user_role").
+//		Where(data.Eq("user_role.role_name", "admin")).
+//		Where(data.Or(data.IsNull("user_last"), data.Like("user_email", "%@example.com"))).
+//		OrderBy("user_last").
+//		Limit(20).
+//		All()
+type SelectQuery[T any] struct {
+	dao       *DAO[T]
+	columns   []string
+	joins     []join
+	where     []Condition
+	orderBy   []ordering
+	limit     int
+	offset    int
+	forUpdate bool
+	err       error
+}
+
+// Select starts a query on the DAO's table. With no columns it selects every column of the table.
+func (dao *DAO[T]) Select(columns ...string) *SelectQuery[T] {
+	return &SelectQuery[T]{dao: dao, columns: columns, limit: -1, offset: -1}
+}
+
+// Where narrows the query to rows meeting every condition. Repeated calls are ANDed together.
+func (q *SelectQuery[T]) Where(conditions ...Condition) *SelectQuery[T] {
+	q.where = append(q.where, conditions...)
+	return q
+}
+
+// Join adds an inner join to the table referenced by column's foreign key
+func (q *SelectQuery[T]) Join(column string) *SelectQuery[T] {
+	return q.addJoin("JOIN", column)
+}
+
+// LeftJoin adds a left outer join to the table referenced by column's foreign key
+func (q *SelectQuery[T]) LeftJoin(column string) *SelectQuery[T] {
+	return q.addJoin("LEFT JOIN", column)
+}
+
+func (q *SelectQuery[T]) addJoin(kind string, columnName string) *SelectQuery[T] {
+	for _, column := range tableColumns(q.dao.Table) {
+		if column.Name != columnName {
+			continue
+		}
+		fk := column.ForeignKey
+		if fk == nil || fk.Table == nil || fk.Column == nil {
+			break
+		}
+		q.joins = append(q.joins, join{
+			kind:  kind,
+			table: fk.Table.Name,
+			on:    [2]string{q.dao.Table.Name + "." + column.Name, fk.Table.Name + "." + fk.Column.Name},
+		})
+		return q
+	}
+	if q.err == nil {
+		q.err = fmt.Errorf("cannot join on %s.%s: no such foreign key", q.dao.Table.Name, columnName)
+	}
+	return q
+}
+
+// OrderBy sorts the results by column, ascending
+func (q *SelectQuery[T]) OrderBy(column string) *SelectQuery[T] {
+	q.orderBy = append(q.orderBy, ordering{column: column})
+	return q
+}
+
+// OrderByDesc sorts the results by column, descending
+func (q *SelectQuery[T]) OrderByDesc(column string) *SelectQuery[T] {
+	q.orderBy = append(q.orderBy, ordering{column: column, desc: true})
+	return q
+}
+
+// Limit returns at most n rows
+func (q *SelectQuery[T]) Limit(n int) *SelectQuery[T] {
+	q.limit = n
+	return q
+}
+
+// Offset skips the first n rows
+func (q *SelectQuery[T]) Offset(n int) *SelectQuery[T] {
+	q.offset = n
+	return q
+}
+
+// ForUpdate locks the selected rows until the end of the transaction the query runs in
+func (q *SelectQuery[T]) ForUpdate() *SelectQuery[T] {
+	q.forUpdate = true
+	return q
+}
+
+// SQL renders the query and the arguments bound to it
+func (q *SelectQuery[T]) SQL() (string, []any, error) {
+	if q.err != nil {
+		return "", nil, q.err
+	}
+
+	w := &sqlWriter{dialect: q.dao.ISession.Dialect()}
+	w.WriteString("SELECT ")
+	switch {
+	case len(q.columns) > 0:
+		for i, column := range q.columns {
+			if i > 0 {
+				w.WriteString(", ")
+			}
+			w.column(column)
+		}
+	case len(q.joins) > 0:
+		// Keep the joined tables' columns from clashing with the ones T maps
+		w.column(q.dao.Table.Name + ".*")
+	default:
+		w.WriteString("*")
+	}
+
+	w.WriteString(" FROM ")
+	w.column(q.dao.Table.Name)
+	for _, j := range q.joins {
+		w.WriteString(" " + j.kind + " ")
+		w.column(j.table)
+		w.WriteString(" ON ")
+		w.column(j.on[0])
+		w.WriteString(" = ")
+		w.column(j.on[1])
+	}
+
+	if len(q.where) > 0 {
+		w.WriteString(" WHERE ")
+		And(q.where...).writeSQL(w)
+	}
+
+	if len(q.orderBy) > 0 {
+		w.WriteString(" ORDER BY ")
+		for i, order := range q.orderBy {
+			if i > 0 {
+				w.WriteString(", ")
+			}
+			w.column(order.column)
+			if order.desc {
+				w.WriteString(" DESC")
+			}
+		}
+	}
+
+	if limit := w.dialect.Limit(q.limit, q.offset); limit != "" {
+		w.WriteString(" " + limit)
+	}
+	if q.forUpdate {
+		w.WriteString(" FOR UPDATE")
+	}
+
+	return w.builder.String(), w.args, nil
+}
+
+// Rows runs the query and returns the open result set; the caller must close it
+func (q *SelectQuery[T]) Rows() (*sql.Rows, error) {
+	query, args, err := q.SQL()
+	if err != nil {
+		return nil, err
+	}
+	return q.dao.ISession.Query(query, args...)
+}
+
+// All runs the query and scans every row into a T
+func (q *SelectQuery[T]) All() ([]T, error) {
+	rows, err := q.Rows()
+	if err != nil {
+		return nil, err
+	}
+	return ScanRows[T](rows)
+}
+
+// One runs the query and scans the first row into a T. It returns ErrNotFound if there is none.
+// Unless a limit is set, the query is limited to one row.
+func (q *SelectQuery[T]) One() (T, error) {
+	query := *q
+	if query.limit < 0 {
+		query.limit = 1
+	}
+	rows, err := query.Rows()
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return ScanOne[T](rows)
+}
+
+// Each runs the query and yields its rows one at a time as they are read, rather than loading them
+// all as All does. The rows are closed when the loop ends or breaks, and an error is yielded once,
+// ending the loop. The query runs afresh each time the sequence is ranged over.
+//
+// E.g.,
+//
+//	for user, err := range dao.Select().OrderBy("user_id").Each() {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+func (q *SelectQuery[T]) Each() iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		rows, err := q.Rows()
+		if err != nil {
+			var zero T
+			yield(zero, err)
+			return
+		}
+		for item, err := range EachRow[T](rows) {
+			if !yield(item, err) {
+				return
+			}
+		}
+	}
+}
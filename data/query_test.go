@@ -0,0 +1,232 @@
+package data
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"gormless/data/dialect"
+	"regexp"
+	"testing"
+)
+
+func joinedUserTable() Table {
+	roleTable := Table{Name: "user_role"}
+	roleColumn := Column{Name: "role_id"}
+	table := testUserTable()
+	*table.Columns = append(*table.Columns, Column{
+		Name:       "user_role",
+		Type:       stringPtr(dialect.PsqlInt),
+		ForeignKey: &ForeignKey{Table: &roleTable, Column: &roleColumn},
+	})
+	return table
+}
+
+func TestSelectQuerySQL(t *testing.T) {
+	tests := []struct {
+		name     string
+		dialect  dialect.Dialect
+		build    func(dao *DAO[testUser]) *SelectQuery[testUser]
+		expected string
+		args     []any
+	}{
+		{
+			name:     "Everything",
+			dialect:  dialect.PostgresDialect{},
+			build:    func(dao *DAO[testUser]) *SelectQuery[testUser] { return dao.Select() },
+			expected: `SELECT * FROM "user"`,
+		},
+		{
+			name:    "Conditions are ANDed",
+			dialect: dialect.PostgresDialect{},
+			build: func(dao *DAO[testUser]) *SelectQuery[testUser] {
+				return dao.Select("user_id", "user_email").
+					Where(Eq("user_first", "John"), GtEq("user_id", 10)).
+					Where(IsNotNull("user_email"))
+			},
+			expected: `SELECT "user_id", "user_email" FROM "user" WHERE ("user_first" = $1 AND "user_id" >= $2 AND "user_email" IS NOT NULL)`,
+			args:     []any{"John", 10},
+		},
+		{
+			name:    "Groups, lists and ranges",
+			dialect: dialect.PostgresDialect{},
+			build: func(dao *DAO[testUser]) *SelectQuery[testUser] {
+				return dao.Select().
+					Where(Or(In("user_id", 1, 2, 3), Between("user_id", 10, 20), Not(Like("user_email", "%@example.com")))).
+					Where(In("user_first"))
+			},
+			expected: `SELECT * FROM "user" WHERE (("user_id" IN ($1, $2, $3) OR "user_id" BETWEEN $4 AND $5 OR NOT ("user_email" LIKE $6)) AND 1 = 0)`,
+			args:     []any{1, 2, 3, 10, 20, "%@example.com"},
+		},
+		{
+			name:    "Order, limit and offset",
+			dialect: dialect.PostgresDialect{},
+			build: func(dao *DAO[testUser]) *SelectQuery[testUser] {
+				return dao.Select().Where(IsNull("user_email")).OrderBy("user_first").OrderByDesc("user_id").Limit(10).Offset(30)
+			},
+			expected: `SELECT * FROM "user" WHERE "user_email" IS NULL ORDER BY "user_first", "user_id" DESC LIMIT 10 OFFSET 30`,
+		},
+		{
+			name:    "Join through a foreign key",
+			dialect: dialect.PostgresDialect{},
+			build: func(dao *DAO[testUser]) *SelectQuery[testUser] {
+				return dao.Select().Join("user_role").Where(Eq("user_role.role_name", "admin"))
+			},
+			expected: `SELECT "user".* FROM "user" JOIN "user_role" ON "user"."user_role" = "user_role"."role_id" WHERE "user_role"."role_name" = $1`,
+			args:     []any{"admin"},
+		},
+		{
+			name:    "MySQL",
+			dialect: dialect.MySQLDialect{},
+			build: func(dao *DAO[testUser]) *SelectQuery[testUser] {
+				return dao.Select().LeftJoin("user_role").Where(NotEq("user_id", 1), LtEq("user_id", 9)).Offset(5)
+			},
+			expected: "SELECT `user`.* FROM `user` LEFT JOIN `user_role` ON `user`.`user_role` = `user_role`.`role_id` " +
+				"WHERE (`user_id` <> ? AND `user_id` <= ?) LIMIT 18446744073709551615 OFFSET 5",
+			args: []any{1, 9},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session, _ := newRecordingSession(tt.dialect)
+			dao := &DAO[testUser]{ISession: session, Table: joinedUserTable()}
+
+			query, args, err := tt.build(dao).SQL()
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, query)
+			assert.Equal(t, tt.args, args)
+		})
+	}
+}
+
+func TestSelectQueryJoinNeedsForeignKey(t *testing.T) {
+	session, _ := newRecordingSession(dialect.PostgresDialect{})
+	dao := &DAO[testUser]{ISession: session, Table: joinedUserTable()}
+
+	_, err := dao.Select().Join("user_email").All()
+
+	assert.EqualError(t, err, "cannot join on user.user_email: no such foreign key")
+}
+
+func TestSelectQueryOne(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	session := &Session{DB: db}
+	session.SQLDialect = dialect.PostgresDialect{}
+	dao := DAO[testUser]{ISession: session, Table: testUserTable()}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "user" WHERE "user_email" = $1 LIMIT 1`)).
+		WithArgs("john@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "user_first", "user_email"}).
+			AddRow(1, "John", "john@example.com"))
+
+	user, err := dao.Select().Where(Eq("user_email", "john@example.com")).One()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "John", user.FirstName)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSelectQueryOneLeavesQueryAsItWas(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	session := &Session{DB: db}
+	session.SQLDialect = dialect.PostgresDialect{}
+	dao := DAO[testUser]{ISession: session, Table: testUserTable()}
+	query := dao.Select().Where(Eq("user_first", "Bob"))
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "user" WHERE "user_first" = $1 LIMIT 1`)).
+		WithArgs("Bob").
+		WillReturnRows(userRows().AddRow(1, "Bob", "a@x"))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "user" WHERE "user_first" = $1`) + "$").
+		WithArgs("Bob").
+		WillReturnRows(userRows().AddRow(1, "Bob", "a@x").AddRow(2, "Bob", "b@x"))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "user" WHERE "user_first" = $1 ORDER BY "user"."user_id" LIMIT 2`)).
+		WithArgs("Bob").
+		WillReturnRows(userRows().AddRow(1, "Bob", "a@x"))
+
+	_, err = query.One()
+	assert.NoError(t, err)
+
+	all, err := query.All()
+	assert.NoError(t, err)
+	assert.Len(t, all, 2)
+
+	_, err = query.Page(PageRequest{Size: 1})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDAOEach(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	session := &Session{DB: db}
+	session.SQLDialect = dialect.PostgresDialect{}
+	dao := DAO[testUser]{ISession: session, Table: testUserTable()}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "user" WHERE "user_first" LIKE $1`)).
+		WithArgs("J%").
+		WillReturnRows(userRows().AddRow(1, "John", "john@x").AddRow(2, "Jane", "jane@x")).
+		RowsWillBeClosed()
+	// Breaking out of the loop still closes the rows
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "user"`)).
+		WillReturnRows(userRows().AddRow(1, "John", "john@x").AddRow(2, "Jane", "jane@x")).
+		RowsWillBeClosed()
+
+	var names []string
+	for user, err := range dao.Each(Like("user_first", "J%")) {
+		assert.NoError(t, err)
+		names = append(names, user.FirstName)
+	}
+	assert.Equal(t, []string{"John", "Jane"}, names)
+
+	count := 0
+	for _, err := range dao.Each() {
+		assert.NoError(t, err)
+		count++
+		break
+	}
+	assert.Equal(t, 1, count)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDAOEachYieldsErrors(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	session := &Session{DB: db}
+	session.SQLDialect = dialect.PostgresDialect{}
+	dao := DAO[testUser]{ISession: session, Table: testUserTable()}
+
+	mock.ExpectQuery("SELECT").WillReturnError(assert.AnError)
+	mock.ExpectQuery("SELECT").
+		WillReturnRows(userRows().AddRow(1, "John", "john@x").AddRow(2, "Jane", "jane@x").RowError(1, assert.AnError))
+
+	var errs []error
+	for _, err := range dao.Each() {
+		errs = append(errs, err)
+	}
+	assert.Equal(t, []error{assert.AnError}, errs)
+
+	errs = nil
+	for _, err := range dao.Each() {
+		errs = append(errs, err)
+	}
+	assert.Equal(t, []error{nil, assert.AnError}, errs)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
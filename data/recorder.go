@@ -0,0 +1,206 @@
+package data
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gormless/data/dialect"
+	"io"
+	"sync"
+)
+
+// Statement is a single SQL statement with the arguments bound to it
+type Statement struct {
+	SQL   string
+	Args  []any
+	Query bool // The statement was issued as a query rather than executed
+}
+
+// recorder is a database/sql connector that records every statement it is given instead of
+// running it. Executions report zero rows affected and queries return no rows.
+type recorder struct {
+	mu         sync.Mutex
+	statements []Statement
+}
+
+// newRecordingSession returns a session whose statements are captured by rec
+func newRecordingSession(d dialect.Dialect) (*Session, *recorder) {
+	rec := &recorder{}
+	return &Session{DB: sql.OpenDB(rec), SQLDialect: d}, rec
+}
+
+func (r *recorder) record(statement Statement) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.statements = append(r.statements, statement)
+}
+
+// Statements returns everything recorded so far, in order
+func (r *recorder) Statements() []Statement {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Statement(nil), r.statements...)
+}
+
+func (r *recorder) Connect(ctx context.Context) (driver.Conn, error) {
+	return &recorderConn{rec: r}, nil
+}
+
+func (r *recorder) Driver() driver.Driver {
+	return recorderDriver{}
+}
+
+type recorderDriver struct{}
+
+func (recorderDriver) Open(name string) (driver.Conn, error) {
+	return nil, errors.New("the recording driver can only be used through its connector")
+}
+
+type recorderConn struct {
+	rec *recorder
+}
+
+func (c *recorderConn) Prepare(query string) (driver.Stmt, error) {
+	return &recorderStmt{rec: c.rec, query: query}, nil
+}
+
+func (c *recorderConn) Close() error {
+	return nil
+}
+
+func (c *recorderConn) Begin() (driver.Tx, error) {
+	c.rec.record(Statement{SQL: "BEGIN"})
+	return &recorderTx{rec: c.rec}, nil
+}
+
+// Ping lets sessions built on the recorder pass the Ping check table initializers make
+func (c *recorderConn) Ping(ctx context.Context) error {
+	return nil
+}
+
+type recorderTx struct {
+	rec *recorder
+}
+
+func (t *recorderTx) Commit() error {
+	t.rec.record(Statement{SQL: "COMMIT"})
+	return nil
+}
+
+func (t *recorderTx) Rollback() error {
+	t.rec.record(Statement{SQL: "ROLLBACK"})
+	return nil
+}
+
+type recorderStmt struct {
+	rec   *recorder
+	query string
+}
+
+func (s *recorderStmt) Close() error {
+	return nil
+}
+
+// NumInput returns -1 so database/sql doesn't check the argument count
+func (s *recorderStmt) NumInput() int {
+	return -1
+}
+
+func (s *recorderStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.rec.record(Statement{SQL: s.query, Args: valuesToArgs(args)})
+	return driver.RowsAffected(0), nil
+}
+
+func (s *recorderStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.rec.record(Statement{SQL: s.query, Args: valuesToArgs(args), Query: true})
+	return emptyRows{}, nil
+}
+
+func valuesToArgs(values []driver.Value) []any {
+	if len(values) == 0 {
+		return nil
+	}
+	args := make([]any, len(values))
+	for i, value := range values {
+		args[i] = value
+	}
+	return args
+}
+
+// emptyRows is the result of every query run against the recorder
+type emptyRows struct{}
+
+func (emptyRows) Columns() []string {
+	return nil
+}
+
+func (emptyRows) Close() error {
+	return nil
+}
+
+func (emptyRows) Next(dest []driver.Value) error {
+	return io.EOF
+}
+
+// capturingSession is an ISession that notes every statement executed or prepared through it,
+// including inside transactions it begins, so a migration's checksum can be taken from the SQL it
+// runs. Queries aren't noted: they don't change the schema.
+type capturingSession struct {
+	ISession
+	statements *[]Statement
+}
+
+// newCapturingSession wraps session so the statements run through it are captured
+func newCapturingSession(session ISession) *capturingSession {
+	return &capturingSession{ISession: session, statements: new([]Statement)}
+}
+
+// wrap captures tx's statements along with this session's
+func (s *capturingSession) wrap(tx ISession) ISession {
+	return &capturingSession{ISession: tx, statements: s.statements}
+}
+
+// checksum returns the SHA-256 of the captured statements
+func (s *capturingSession) checksum() string {
+	hash := sha256.New()
+	for _, statement := range *s.statements {
+		fmt.Fprintf(hash, "%s\n%v\n", statement.SQL, statement.Args)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func (s *capturingSession) Prepare(query string) (*sql.Stmt, error) {
+	*s.statements = append(*s.statements, Statement{SQL: query})
+	return s.ISession.Prepare(query)
+}
+
+func (s *capturingSession) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	*s.statements = append(*s.statements, Statement{SQL: query})
+	return s.ISession.PrepareContext(ctx, query)
+}
+
+func (s *capturingSession) Exec(query string, args ...interface{}) (sql.Result, error) {
+	*s.statements = append(*s.statements, Statement{SQL: query, Args: args})
+	return s.ISession.Exec(query, args...)
+}
+
+func (s *capturingSession) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	*s.statements = append(*s.statements, Statement{SQL: query, Args: args})
+	return s.ISession.ExecContext(ctx, query, args...)
+}
+
+func (s *capturingSession) WithTx(fn func(tx ISession) error) error {
+	return s.ISession.WithTx(func(tx ISession) error {
+		return fn(s.wrap(tx))
+	})
+}
+
+func (s *capturingSession) WithTxOptions(options TxOptions, fn func(tx ISession) error) error {
+	return s.ISession.WithTxOptions(options, func(tx ISession) error {
+		return fn(s.wrap(tx))
+	})
+}
@@ -0,0 +1,106 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryPolicy says how often and how soon a transaction is run again after an error its dialect
+// reports as retryable, e.g. a serialization failure on PostgreSQL or a deadlock on MySQL
+type RetryPolicy struct {
+	MaxAttempts    int           // Runs in all, counting the first; zero or one never retries
+	InitialBackoff time.Duration // Wait before the first retry, doubled before each one after
+	MaxBackoff     time.Duration // Longest wait between attempts; zero leaves it uncapped
+	Jitter         float64       // Fraction of each wait, from 0 to 1, that is randomised
+}
+
+// DefaultRetryPolicy suits short transactions that contend for the same rows
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    5,
+	InitialBackoff: 20 * time.Millisecond,
+	MaxBackoff:     time.Second,
+	Jitter:         0.5,
+}
+
+// backoff returns how long to wait after the given failed attempt, counting from 1
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	wait := p.InitialBackoff
+	for i := 1; i < attempt && (p.MaxBackoff <= 0 || wait < p.MaxBackoff); i++ {
+		wait *= 2
+	}
+	if p.MaxBackoff > 0 {
+		wait = min(wait, p.MaxBackoff)
+	}
+
+	jitter := min(max(p.Jitter, 0), 1)
+	return wait - time.Duration(rand.Float64()*jitter*float64(wait))
+}
+
+// TxOptions configure a transaction run by WithTxOptions
+type TxOptions struct {
+	Isolation sql.IsolationLevel // Defaults to the database's own level
+	ReadOnly  bool
+	Retry     RetryPolicy // Defaults to running once
+}
+
+// WithTxOptions runs fn in a transaction as WithTx does, at the isolation level options ask for.
+//
+// If fn, or the commit, fails with an error the dialect reports as retryable, the transaction has
+// been rolled back and fn is run again in a new one, as options.Retry allows. fn must therefore be
+// safe to run more than once: anything it does outside the database should wait until
+// WithTxOptions returns.
+//
+// E.g.,
+//
+//	err := session.WithTxOptions(data.TxOptions{
+//		Isolation: sql.LevelSerializable,
+//		Retry:     data.DefaultRetryPolicy,
+//	}, func(tx data.ISession) error {
+//		return transfer(tx, from, to, amount)
+//	})
+func (s *Session) WithTxOptions(options TxOptions, fn func(tx ISession) error) error {
+	return retryTx(context.Background(), s, options, fn)
+}
+
+// WithTxOptions nests fn under a SAVEPOINT as WithTx does. A transaction's isolation level is set
+// when it begins, and only the outermost one can be retried, so options are ignored.
+func (s *TxSession) WithTxOptions(options TxOptions, fn func(tx ISession) error) error {
+	return runTx(context.Background(), s, nil, fn)
+}
+
+// WithTxOptions runs fn as Session.WithTxOptions does, under the session's context. The wait
+// between attempts ends early if the context is done.
+func (s *contextSession) WithTxOptions(options TxOptions, fn func(tx ISession) error) error {
+	return retryTx(s.ctx, s, options, fn)
+}
+
+// retryTx runs fn in a transaction until it succeeds, fails for a reason that isn't retryable, or
+// runs out of attempts
+func retryTx(ctx context.Context, session ISession, options TxOptions, fn func(tx ISession) error) error {
+	if _, ok := unwrapSession(session).(*TxSession); ok {
+		return runTx(ctx, session, nil, fn)
+	}
+
+	txOptions := &sql.TxOptions{Isolation: options.Isolation, ReadOnly: options.ReadOnly}
+	for attempt := 1; ; attempt++ {
+		err := runTx(ctx, session, txOptions, fn)
+		if err == nil || !session.Dialect().Retryable(err) {
+			return err
+		}
+		if attempt >= options.Retry.MaxAttempts {
+			if attempt > 1 {
+				return fmt.Errorf("transaction failed after %d attempts: %w", attempt, err)
+			}
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(options.Retry.backoff(attempt)):
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package data
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"gormless/data/dialect"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestWithTxOptionsRetriesSerializationFailures(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	session := &Session{DB: db}
+	session.SQLDialect = dialect.PostgresDialect{}
+
+	update := regexp.QuoteMeta("UPDATE account SET balance = balance - 10")
+	mock.ExpectBegin()
+	mock.ExpectExec(update).WillReturnError(&pq.Error{Code: "40001"})
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	mock.ExpectExec(update).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	attempts := 0
+	options := TxOptions{Retry: RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}}
+	err = session.WithTxOptions(options, func(tx ISession) error {
+		attempts++
+		_, err := tx.Exec("UPDATE account SET balance = balance - 10")
+		return err
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, attempts)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWithTxOptionsGivesUp(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	session := &Session{DB: db}
+	session.SQLDialect = dialect.PostgresDialect{}
+
+	update := regexp.QuoteMeta("UPDATE account SET balance = balance - 10")
+	for range 2 {
+		mock.ExpectBegin()
+		mock.ExpectExec(update).WillReturnError(&pq.Error{Code: "40001"})
+		mock.ExpectRollback()
+	}
+	// A unique violation isn't retried
+	mock.ExpectBegin()
+	mock.ExpectExec(update).WillReturnError(&pq.Error{Code: "23505"})
+	mock.ExpectRollback()
+
+	run := func(tx ISession) error {
+		_, err := tx.Exec("UPDATE account SET balance = balance - 10")
+		return err
+	}
+	options := TxOptions{Retry: RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}}
+
+	err = session.WithTxOptions(options, run)
+	assert.ErrorContains(t, err, "transaction failed after 2 attempts")
+	var pqErr *pq.Error
+	assert.ErrorAs(t, err, &pqErr)
+
+	err = session.WithTxOptions(options, run)
+	assert.ErrorAs(t, err, &pqErr)
+	assert.Equal(t, pq.ErrorCode("23505"), pqErr.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{InitialBackoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond}
+	assert.Equal(t, 10*time.Millisecond, policy.backoff(1))
+	assert.Equal(t, 20*time.Millisecond, policy.backoff(2))
+	assert.Equal(t, 40*time.Millisecond, policy.backoff(3))
+	assert.Equal(t, 50*time.Millisecond, policy.backoff(4))
+	assert.Equal(t, 50*time.Millisecond, policy.backoff(40))
+
+	policy.Jitter = 0.5
+	for range 100 {
+		wait := policy.backoff(2)
+		assert.GreaterOrEqual(t, wait, 10*time.Millisecond)
+		assert.LessOrEqual(t, wait, 20*time.Millisecond)
+	}
+}
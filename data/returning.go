@@ -0,0 +1,226 @@
+package data
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// InsertReturning inserts rows and returns them as stored, with generated keys and column defaults
+// filled in, scanned into T.
+//
+// PostgreSQL hands the rows back from a RETURNING clause. MySQL has no RETURNING, so there each row
+// is inserted on its own and read back in the same transaction, by its primary key or, for an
+// AUTO_INCREMENT key, by LastInsertId.
+func (dao *DAO[T]) InsertReturning(rows ...map[string]any) ([]T, error) {
+	return dao.writeReturning("insert", rows, nil)
+}
+
+// UpsertReturning upserts rows as UpsertWith does and returns the rows it inserted or updated.
+// Rows left alone, e.g. by DoNothing, aren't returned. On MySQL, rows are read back as with
+// InsertReturning, falling back to the conflict target for rows that were updated.
+func (dao *DAO[T]) UpsertReturning(options UpsertOptions, rows ...map[string]any) ([]T, error) {
+	return dao.writeReturning("upsert", rows, &options)
+}
+
+// InsertStructReturning inserts items as InsertStruct does and returns them as stored, e.g. with
+// their serial IDs set
+func (dao *DAO[T]) InsertStructReturning(items ...T) ([]T, error) {
+	return dao.writeStructsReturning("insert", items, nil)
+}
+
+// UpsertStructReturning upserts items as UpsertStructWith does and returns the rows it inserted or
+// updated
+func (dao *DAO[T]) UpsertStructReturning(options UpsertOptions, items ...T) ([]T, error) {
+	return dao.writeStructsReturning("upsert", items, &options)
+}
+
+// UpdateByPKReturning updates a row as UpdateByPK does and returns it as stored. It returns
+// ErrNotFound if no row has the key.
+func (dao *DAO[T]) UpdateByPKReturning(values map[string]any) (T, error) {
+	var zero T
+	w, condition, err := dao.updateStatement(values)
+	if err != nil {
+		return zero, err
+	}
+
+	if returning := dao.ISession.Dialect().Returning(); returning != "" {
+		query := w.builder.String() + returning
+		rows, err := dao.ISession.Query(query, w.args...)
+		if err != nil {
+			return zero, fmt.Errorf("update failed: %w", dao.statementError(query, err))
+		}
+		return ScanOne[T](rows)
+	}
+
+	var item T
+	err = inTransaction(dao.ISession, func(tx ISession) error {
+		txDAO := *dao
+		txDAO.ISession = tx
+		if _, err := txDAO.execAffected("update", w); err != nil {
+			return err
+		}
+		item, err = txDAO.Select().Where(condition).One()
+		return err
+	})
+	return item, err
+}
+
+// UpdateStructReturning updates a row as UpdateStruct does and returns it as stored, e.g. with
+// readonly columns the database maintains. It returns ErrNotFound if no row has item's key.
+func (dao *DAO[T]) UpdateStructReturning(item T) (T, error) {
+	row, err := dao.rowFromStruct(item)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return dao.UpdateByPKReturning(row)
+}
+
+// DeleteWhereReturning deletes rows as DeleteWhere does and returns them as they were. On MySQL
+// the rows are selected FOR UPDATE and then deleted, in one transaction.
+func (dao *DAO[T]) DeleteWhereReturning(conditions ...Condition) ([]T, error) {
+	w, err := dao.deleteStatement(conditions)
+	if err != nil {
+		return nil, err
+	}
+
+	if returning := dao.ISession.Dialect().Returning(); returning != "" {
+		return dao.queryReturning("delete", w.builder.String()+returning, w.args)
+	}
+
+	var deleted []T
+	err = inTransaction(dao.ISession, func(tx ISession) error {
+		txDAO := *dao
+		txDAO.ISession = tx
+		deleted, err = txDAO.Select().Where(conditions...).ForUpdate().All()
+		if err != nil {
+			return err
+		}
+		_, err = txDAO.execAffected("delete", w)
+		return err
+	})
+	return deleted, err
+}
+
+// writeStructsReturning converts items to rows and writes them with writeReturning
+func (dao *DAO[T]) writeStructsReturning(action string, items []T, options *UpsertOptions) ([]T, error) {
+	batches, err := dao.structBatches(items)
+	if err != nil {
+		return nil, err
+	}
+
+	var written []T
+	err = dao.eachBatch(batches, func(dao *DAO[T], rows []map[string]any) error {
+		items, err := dao.writeReturning(action, rows, options)
+		written = append(written, items...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return written, nil
+}
+
+// writeReturning inserts rows, or upserts them given options, and returns what was written
+func (dao *DAO[T]) writeReturning(action string, rows []map[string]any, options *UpsertOptions) ([]T, error) {
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	statement := func(dao *DAO[T], rows []map[string]any) (string, []any) {
+		if options != nil {
+			return dao.upsertStatement(*options, rows)
+		}
+		return dao.insertStatement(rows)
+	}
+
+	if returning := dao.ISession.Dialect().Returning(); returning != "" {
+		query, args := statement(dao, rows)
+		return dao.queryReturning(action, query+returning, args)
+	}
+
+	var written []T
+	err := inTransaction(dao.ISession, func(tx ISession) error {
+		txDAO := *dao
+		txDAO.ISession = tx
+		for _, row := range rows {
+			query, args := statement(&txDAO, []map[string]any{row})
+			result, err := tx.Exec(query, args...)
+			if err != nil {
+				return fmt.Errorf("%s failed: %w", action, txDAO.statementError(query, err))
+			}
+
+			condition, err := txDAO.writtenRow(row, result, options)
+			if err != nil {
+				return err
+			}
+			if condition == nil {
+				continue // The upsert left an existing row alone
+			}
+			item, err := txDAO.Select().Where(condition).One()
+			if err != nil {
+				return fmt.Errorf("reading back the row written to %s: %w", dao.Table.Name, err)
+			}
+			written = append(written, item)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return written, nil
+}
+
+// queryReturning runs a statement with a RETURNING clause and scans what it returns
+func (dao *DAO[T]) queryReturning(action string, query string, args []any) ([]T, error) {
+	rows, err := dao.ISession.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s failed: %w", action, dao.statementError(query, err))
+	}
+	return ScanRows[T](rows)
+}
+
+// writtenRow returns a condition matching the row a single-row insert or upsert wrote, or nil if
+// an upsert left the existing row as it was
+func (dao *DAO[T]) writtenRow(row map[string]any, result sql.Result, options *UpsertOptions) (Condition, error) {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("reading rows affected: %w", err)
+	}
+	// MySQL counts an inserted row once, an updated row twice and an unchanged row not at all
+	if options != nil && affected == 0 {
+		return nil, nil
+	}
+
+	keys, _ := dao.primaryKey()
+	if condition := rowCondition(row, keys); condition != nil {
+		return condition, nil
+	}
+	if len(keys) == 1 && affected == 1 {
+		if id, err := result.LastInsertId(); err == nil && id > 0 {
+			return Eq(keys[0], id), nil
+		}
+	}
+	if options != nil {
+		if condition := rowCondition(row, dao.conflictTarget(*options)); condition != nil {
+			return condition, nil
+		}
+	}
+	return nil, fmt.Errorf("cannot read back the row written to %s: its primary key isn't known", dao.Table.Name)
+}
+
+// rowCondition matches on the row's values for columns, or returns nil if the row lacks any of them
+func rowCondition(row map[string]any, columns []string) Condition {
+	if len(columns) == 0 {
+		return nil
+	}
+	conditions := make([]Condition, len(columns))
+	for i, column := range columns {
+		value, ok := row[column]
+		if !ok || value == nil {
+			return nil
+		}
+		conditions[i] = Eq(column, value)
+	}
+	return And(conditions...)
+}
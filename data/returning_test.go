@@ -0,0 +1,188 @@
+package data
+
+import (
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"gormless/data/dialect"
+	"regexp"
+	"testing"
+)
+
+func TestInsertStructReturningPostgres(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	session := &Session{DB: db}
+	session.SQLDialect = dialect.PostgresDialect{}
+	dao := DAO[testUser]{ISession: session, Table: testUserTable()}
+
+	mock.ExpectQuery(regexp.QuoteMeta(
+		`INSERT INTO "user" ("user_email", "user_first") VALUES ($1, $2), ($3, $4) RETURNING *`)).
+		WithArgs("john@x", "John", "jane@x", "Jane").
+		WillReturnRows(userRows().AddRow(1, "John", "john@x").AddRow(2, "Jane", "jane@x"))
+
+	users, err := dao.InsertStructReturning(
+		testUser{FirstName: "John", Email: "john@x"},
+		testUser{FirstName: "Jane", Email: "jane@x"},
+	)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2}, userIDs(users))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInsertReturningMySQL(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	session := &Session{DB: db}
+	session.SQLDialect = dialect.MySQLDialect{}
+	dao := DAO[map[string]any]{ISession: session, Table: testUserTable()}
+
+	// Each row is inserted and read back by its new ID, in one transaction
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `user` (`user_first`) VALUES (?)")).
+		WithArgs("John").
+		WillReturnResult(sqlmock.NewResult(41, 1))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `user` WHERE `user_id` = ? LIMIT 1")).
+		WithArgs(41).
+		WillReturnRows(userRows().AddRow(41, "John", nil))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO `user` (`user_first`) VALUES (?)")).
+		WithArgs("Jane").
+		WillReturnResult(sqlmock.NewResult(42, 1))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `user` WHERE `user_id` = ? LIMIT 1")).
+		WithArgs(42).
+		WillReturnRows(userRows().AddRow(42, "Jane", nil))
+	mock.ExpectCommit()
+
+	users, err := dao.InsertReturning(map[string]any{"user_first": "John"}, map[string]any{"user_first": "Jane"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []map[string]any{
+		{"user_id": int64(41), "user_first": "John", "user_email": nil},
+		{"user_id": int64(42), "user_first": "Jane", "user_email": nil},
+	}, users)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpsertReturningMySQL(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	session := &Session{DB: db}
+	session.SQLDialect = dialect.MySQLDialect{}
+	dao := DAO[testUser]{ISession: session, Table: testUserTable()}
+	options := UpsertOptions{Target: []string{"user_email"}}
+
+	mock.ExpectBegin()
+	// Updated: MySQL reports two rows, and the row is found by its conflict target
+	mock.ExpectExec("INSERT INTO `user`").
+		WithArgs("john@x", "John").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `user` WHERE `user_email` = ? LIMIT 1")).
+		WithArgs("john@x").
+		WillReturnRows(userRows().AddRow(7, "John", "john@x"))
+	// Unchanged: nothing to return
+	mock.ExpectExec("INSERT INTO `user`").
+		WithArgs("jane@x", "Jane").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	users, err := dao.UpsertReturning(options,
+		map[string]any{"user_email": "john@x", "user_first": "John"},
+		map[string]any{"user_email": "jane@x", "user_first": "Jane"},
+	)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []int{7}, userIDs(users))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateAndDeleteReturning(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	session := &Session{DB: db}
+	session.SQLDialect = dialect.PostgresDialect{}
+	dao := DAO[testUser]{ISession: session, Table: testUserTable()}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`UPDATE "user" SET "user_first" = $1 WHERE "user_id" = $2 RETURNING *`)).
+		WithArgs("Jack", 7).
+		WillReturnRows(userRows().AddRow(7, "Jack", "jack@x"))
+	mock.ExpectQuery(regexp.QuoteMeta(`DELETE FROM "user" WHERE "user_email" LIKE $1 RETURNING *`)).
+		WithArgs("%@y").
+		WillReturnRows(userRows().AddRow(8, "Joe", "joe@y").AddRow(9, "Jim", "jim@y"))
+
+	user, err := dao.UpdateByPKReturning(map[string]any{"user_id": 7, "user_first": "Jack"})
+	assert.NoError(t, err)
+	assert.Equal(t, testUser{ID: 7, FirstName: "Jack", Email: "jack@x"}, user)
+
+	deleted, err := dao.DeleteWhereReturning(Like("user_email", "%@y"))
+	assert.NoError(t, err)
+	assert.Equal(t, []int{8, 9}, userIDs(deleted))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateByPKReturningReturnsStatementErrors(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	session := &Session{DB: db}
+	session.SQLDialect = dialect.PostgresDialect{}
+	dao := DAO[testUser]{ISession: session, Table: testUserTable()}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`UPDATE "user" SET "user_first" = $1 WHERE "user_id" = $2 RETURNING *`)).
+		WillReturnError(assert.AnError)
+
+	_, err = dao.UpdateByPKReturning(map[string]any{"user_id": 7, "user_first": "Jack"})
+
+	var statementErr *StatementError
+	if assert.ErrorAs(t, err, &statementErr) {
+		assert.Equal(t, "user", statementErr.Table)
+		assert.Equal(t, `UPDATE "user" SET "user_first" = $1 WHERE "user_id" = $2 RETURNING *`, statementErr.Statement)
+	}
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteWhereReturningMySQL(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	session := &Session{DB: db}
+	session.SQLDialect = dialect.MySQLDialect{}
+	dao := DAO[testUser]{ISession: session, Table: testUserTable()}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `user` WHERE `user_first` = ? FOR UPDATE")).
+		WithArgs("Joe").
+		WillReturnRows(userRows().AddRow(8, "Joe", "joe@x"))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `user` WHERE `user_first` = ?")).
+		WithArgs("Joe").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	deleted, err := dao.DeleteWhereReturning(Eq("user_first", "Joe"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []int{8}, userIDs(deleted))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -1,12 +1,20 @@
 package data
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	dialect "gormless/data/dialect"
 )
 
-// ISession defines the interface for database operations
+// ISession defines the interface for database operations.
+//
+// Session and TxSession return errors their dialect recognises as a *DatabaseError, e.g. one that
+// matches ErrUniqueViolation.
+//
+// The Context variants stop waiting on the database when ctx is cancelled or its deadline passes.
+// Code that only takes an ISession, such as a DAO, CreateTable or a Migration, can be given a
+// context through WithContext.
 type ISession interface {
 	Prepare(query string) (*sql.Stmt, error)
 	Exec(query string, args ...interface{}) (sql.Result, error)
@@ -17,10 +25,23 @@ type ISession interface {
 	Open(dsn string) error
 	Ping() error
 	Dialect() dialect.Dialect
+
+	// WithTx runs fn in a transaction; see Session.WithTx
+	WithTx(fn func(tx ISession) error) error
+	// WithTxOptions runs fn in a transaction, retrying it as options say; see Session.WithTxOptions
+	WithTxOptions(options TxOptions, fn func(tx ISession) error) error
+
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+	PingContext(ctx context.Context) error
 }
 type Session struct {
 	DB         *sql.DB
 	SQLDialect dialect.Dialect
+	Pool       Pool // Applied by Open
 }
 
 func (s *Session) Dialect() dialect.Dialect {
@@ -28,14 +49,14 @@ func (s *Session) Dialect() dialect.Dialect {
 }
 
 func (s *Session) Prepare(query string) (*sql.Stmt, error) {
-	return s.DB.Prepare(query)
+	return s.PrepareContext(context.Background(), query)
 }
 
 func (s *Session) Exec(query string, args ...interface{}) (sql.Result, error) {
-	return s.DB.Exec(query, args...)
+	return s.ExecContext(context.Background(), query, args...)
 }
 func (s *Session) Query(query string, args ...interface{}) (*sql.Rows, error) {
-	return s.DB.Query(query, args)
+	return s.QueryContext(context.Background(), query, args...)
 }
 
 func (s *Session) QueryRow(query string, args ...interface{}) *sql.Row {
@@ -45,37 +66,240 @@ func (s *Session) QueryRow(query string, args ...interface{}) *sql.Row {
 func (s *Session) Ping() error {
 	return s.DB.Ping()
 }
+
+func (s *Session) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	stmt, err := s.DB.PrepareContext(ctx, query)
+	return stmt, translateError(s, err)
+}
+
+func (s *Session) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	result, err := s.DB.ExecContext(ctx, query, args...)
+	return result, translateError(s, err)
+}
+
+func (s *Session) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	rows, err := s.DB.QueryContext(ctx, query, args...)
+	return rows, translateError(s, err)
+}
+
+func (s *Session) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return s.DB.QueryRowContext(ctx, query, args...)
+}
+
+func (s *Session) PingContext(ctx context.Context) error {
+	return s.DB.PingContext(ctx)
+}
+
 func (s *Session) Close() error {
 	return s.DB.Close()
 }
 
-// Open connects to the database and returns a new session
+// Open connects to the database, sizing the connection pool and setting up each connection as
+// s.Pool says
 func (s *Session) Open(dsn string) error {
-	db, err := sql.Open("postgres", dsn)
+	connector, err := s.SQLDialect.Connector(dsn)
 	if err != nil {
 		return err
 	}
 
+	db := s.Pool.open(connector, s.SQLDialect)
 	err = db.Ping()
 	if err != nil {
+		_ = db.Close()
 		return err
 	}
 	s.DB = db
 	return err
 }
 
+// Stats reports on the connection pool, e.g. how many connections are in use and how long
+// callers have waited for one
+func (s *Session) Stats() sql.DBStats {
+	return s.DB.Stats()
+}
+
 func (s *Session) Begin() (*sql.Tx, error) {
 	return s.DB.Begin()
 }
 
+func (s *Session) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	return s.DB.BeginTx(ctx, opts)
+}
+
 func (s *Session) Commit(tx *sql.Tx) error {
 	return tx.Commit()
 }
 
-// GetDbSession creates a new database session
-func GetDbSession(dsn string, dialectType string) (*Session, error) {
+// Conn reserves a single connection from the pool, for work that must stay on one connection
+func (s *Session) Conn(ctx context.Context) (*sql.Conn, error) {
+	return s.DB.Conn(ctx)
+}
+
+// translateError lets the session's dialect recognise err, e.g. as a unique violation, so callers
+// can match it with errors.Is(err, data.ErrUniqueViolation)
+func translateError(session ISession, err error) error {
+	if err == nil || session.Dialect() == nil {
+		return err
+	}
+	return session.Dialect().TranslateError(err)
+}
+
+// TxSession is an ISession bound to an open transaction, so migrations and DAOs can run inside it.
+// It is the tx that WithTx hands to its function.
+type TxSession struct {
+	Tx     *sql.Tx
+	parent ISession
+	depth  int // Number of savepoints the session is nested in
+}
+
+func (s *TxSession) Dialect() dialect.Dialect {
+	return s.parent.Dialect()
+}
+
+func (s *TxSession) Prepare(query string) (*sql.Stmt, error) {
+	return s.PrepareContext(context.Background(), query)
+}
+
+func (s *TxSession) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return s.ExecContext(context.Background(), query, args...)
+}
+
+func (s *TxSession) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return s.QueryContext(context.Background(), query, args...)
+}
+
+func (s *TxSession) QueryRow(query string, args ...interface{}) *sql.Row {
+	return s.Tx.QueryRow(query, args...)
+}
+
+func (s *TxSession) Ping() error {
+	return s.parent.Ping()
+}
+
+func (s *TxSession) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	stmt, err := s.Tx.PrepareContext(ctx, query)
+	return stmt, translateError(s, err)
+}
+
+func (s *TxSession) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	result, err := s.Tx.ExecContext(ctx, query, args...)
+	return result, translateError(s, err)
+}
+
+func (s *TxSession) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	rows, err := s.Tx.QueryContext(ctx, query, args...)
+	return rows, translateError(s, err)
+}
+
+func (s *TxSession) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return s.Tx.QueryRowContext(ctx, query, args...)
+}
+
+func (s *TxSession) PingContext(ctx context.Context) error {
+	return s.parent.PingContext(ctx)
+}
+
+// Close is a no-op; the transaction is finished by whoever started it
+func (s *TxSession) Close() error {
+	return nil
+}
+
+// Begin fails because the session is already inside a transaction; WithTx nests one instead
+func (s *TxSession) Begin() (*sql.Tx, error) {
+	return nil, fmt.Errorf("session is already in a transaction; use WithTx to nest one")
+}
+
+// BeginTx fails because the session is already inside a transaction
+func (s *TxSession) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	return s.Begin()
+}
+
+// Open fails because a transaction cannot be pointed at a different database
+func (s *TxSession) Open(dsn string) error {
+	return fmt.Errorf("cannot open a connection from inside a transaction")
+}
+
+// contextSession is an ISession whose methods without a context run under ctx
+type contextSession struct {
+	ISession
+	ctx context.Context
+}
+
+// WithContext returns a session that runs every statement under ctx, including those issued
+// through methods that take no context. Passing it to a DAO, CreateTable or a Migration lets ctx
+// cancel their queries or give them a deadline.
+//
+// E.g.,
+//
+//	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+//	defer cancel()
+//	err := data.CreateTable(data.WithContext(ctx, session), table)
+func WithContext(ctx context.Context, session ISession) ISession {
+	switch s := session.(type) {
+	case *contextSession:
+		return &contextSession{ISession: s.ISession, ctx: ctx}
+	case *capturingSession:
+		return s.wrap(WithContext(ctx, s.ISession))
+	}
+	return &contextSession{ISession: session, ctx: ctx}
+}
+
+// sessionContext returns the context session was bound to by WithContext, or context.Background()
+func sessionContext(session ISession) context.Context {
+	switch s := session.(type) {
+	case *contextSession:
+		return s.ctx
+	case *capturingSession:
+		return sessionContext(s.ISession)
+	}
+	return context.Background()
+}
+
+// unwrapSession returns the session beneath any context WithContext bound to it, and beneath the
+// session a migration's statements are captured through
+func unwrapSession(session ISession) ISession {
+	for {
+		switch s := session.(type) {
+		case *contextSession:
+			session = s.ISession
+		case *capturingSession:
+			session = s.ISession
+		default:
+			return session
+		}
+	}
+}
+
+func (s *contextSession) Prepare(query string) (*sql.Stmt, error) {
+	return s.ISession.PrepareContext(s.ctx, query)
+}
+
+func (s *contextSession) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return s.ISession.ExecContext(s.ctx, query, args...)
+}
+
+func (s *contextSession) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return s.ISession.QueryContext(s.ctx, query, args...)
+}
+
+func (s *contextSession) QueryRow(query string, args ...interface{}) *sql.Row {
+	return s.ISession.QueryRowContext(s.ctx, query, args...)
+}
+
+func (s *contextSession) Begin() (*sql.Tx, error) {
+	return s.ISession.BeginTx(s.ctx, nil)
+}
+
+func (s *contextSession) Ping() error {
+	return s.ISession.PingContext(s.ctx)
+}
+
+// GetDbSession creates a new database session whose connection pool is sized, and whose
+// connections' timeouts are set, by pool, e.g. conf.Database.Pool. A zero Pool keeps database/sql's
+// defaults.
+func GetDbSession(dsn string, dialectType string, pool Pool) (*Session, error) {
 	// Create an empty session
-	session := Session{}
+	session := Session{Pool: pool}
 
 	// Set the dialect based on the type
 	switch dialectType {
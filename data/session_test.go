@@ -0,0 +1,95 @@
+package data
+
+import (
+	"context"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"gormless/data/dialect"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestDAOWithContextCancelled(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	session := &Session{DB: db}
+	session.SQLDialect = dialect.PostgresDialect{}
+	dao := DAO[testUser]{ISession: session, Table: testUserTable()}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Nothing reaches the database once the context is done
+	_, err = dao.WithContext(ctx).FindMany("user_first", "J%", true)
+	assert.ErrorIs(t, err, context.Canceled)
+	err = dao.WithContext(ctx).Upsert(map[string]any{"user_id": 1, "user_first": "John"})
+	assert.ErrorIs(t, err, context.Canceled)
+	err = CreateTableContext(ctx, session, testUserTable())
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWithContextDeadlineStopsQuery(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	session := &Session{DB: db}
+	session.SQLDialect = dialect.PostgresDialect{}
+	dao := DAO[testUser]{ISession: session, Table: testUserTable()}
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM \"user\"")).
+		WillDelayFor(time.Second).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	started := time.Now()
+	_, err = dao.WithContext(ctx).Select().All()
+	assert.Error(t, err)
+	assert.Less(t, time.Since(started), time.Second)
+}
+
+type contextKey struct{}
+
+func TestMigrateContextReachesMigrations(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	session := &Session{DB: db}
+	session.SQLDialect = dialect.PostgresDialect{}
+
+	ctx := context.WithValue(context.Background(), contextKey{}, "deploy")
+	var seen any
+	registry := NewMigrationRegistry()
+	assert.NoError(t, registry.Register("0001", Table{Name: "user"}, func(table Table, session ISession) error {
+		seen = sessionContext(session).Value(contextKey{})
+		_, err := session.Exec("ALTER TABLE user first")
+		return err
+	}))
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT \"database_version\", \"version_date\", \"checksum\" FROM \"version\"")).
+		WillReturnRows(sqlmock.NewRows([]string{"database_version", "version_date", "checksum"}))
+	mock.ExpectBegin()
+	mock.ExpectExec("ALTER TABLE user first").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO \"version\"")).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	report, err := NewMigrationRunner(session, registry).MigrateContext(ctx)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"0001"}, report.Applied)
+	assert.Equal(t, "deploy", seen) // The migration ran in a transaction that kept the context
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,32 @@
+package data
+
+import "log"
+
+// fatal ends the process; tests replace it to check that Strict and Must call it
+var fatal = log.Fatal
+
+// Strict returns an initializer that ends the process with log.Fatal if init fails, for programs
+// that cannot run without their tables. Nothing else in this package exits; note that deferred
+// functions, e.g. ones closing the session, don't run either.
+//
+// E.g.,
+//
+//	initUser := data.Strict(tables.InitUserTable(session))
+//	_ = initUser(tables.UserTable())
+func Strict(init TableInitializer) TableInitializer {
+	return func(def TableDef) error {
+		Must(init(def))
+		return nil
+	}
+}
+
+// Must ends the process with log.Fatal if err is not nil, as Strict does for an initializer.
+//
+// E.g.,
+//
+//	data.Must(data.InitDatabaseVersion(session))
+func Must(err error) {
+	if err != nil {
+		fatal(err)
+	}
+}
@@ -0,0 +1,58 @@
+package data
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStrict(t *testing.T) {
+	var exited []any
+	original := fatal
+	fatal = func(v ...any) { exited = append(exited, v...) }
+	defer func() { fatal = original }()
+
+	failure := errors.New("failure")
+	initializer := Strict(func(def TableDef) error { return failure })
+	assert.NoError(t, initializer(nil))
+	assert.Equal(t, []any{failure}, exited)
+
+	Must(nil)
+	assert.Len(t, exited, 1)
+}
+
+// TestNothingExits checks that only Strict and Must can end the process: no other code in the
+// data packages refers to log.Fatal or os.Exit
+func TestNothingExits(t *testing.T) {
+	exits := map[string]bool{
+		"log.Fatal": true, "log.Fatalf": true, "log.Fatalln": true, "os.Exit": true,
+	}
+
+	err := filepath.WalkDir(".", func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+			return err
+		}
+		file, err := parser.ParseFile(token.NewFileSet(), path, nil, 0)
+		if err != nil {
+			return err
+		}
+		ast.Inspect(file, func(node ast.Node) bool {
+			selector, ok := node.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+			if pkg, ok := selector.X.(*ast.Ident); ok && exits[pkg.Name+"."+selector.Sel.Name] && path != "strict.go" {
+				t.Errorf("%s refers to %s.%s", path, pkg.Name, selector.Sel.Name)
+			}
+			return true
+		})
+		return nil
+	})
+	assert.NoError(t, err)
+}
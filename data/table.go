@@ -1,11 +1,11 @@
 package data
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	_ "github.com/lib/pq"
 	"gormless/data/sqlsafe"
-	"log"
 	"strings"
 )
 
@@ -17,11 +17,20 @@ type Table struct {
 	Name       string
 	Columns    *[]Column
 	Migrations *[]Migration
+	Indexes    *[]Index // Indexes beyond the ones implied by Column.Indexed; filled in by IntrospectTable
+}
+
+// Index is a named, possibly multi-column, index on a table
+type Index struct {
+	Name    string
+	Columns []string
+	Unique  bool
 }
 
 type ForeignKey struct {
 	Table  *Table
 	Column *Column
+	Name   string // Constraint name; set by IntrospectTable, needed to drop the constraint
 }
 
 type Column struct {
@@ -35,8 +44,22 @@ type Column struct {
 	Value      *string
 }
 
+// Migration changes a table through the given session. A migration run by MigrateContext or
+// RollbackContext is given a session bound to the run's context, so its statements stop when the
+// context is cancelled.
 type Migration func(table Table, session ISession) error
 
+// ReversibleMigration pairs a forward migration with the migration that undoes it.
+type ReversibleMigration struct {
+	Up   Migration
+	Down Migration
+}
+
+// CreateTableContext creates the table as CreateTable does, running its statements under ctx
+func CreateTableContext(ctx context.Context, session ISession, table Table) error {
+	return CreateTable(WithContext(ctx, session), table)
+}
+
 func CreateTable(session ISession, table Table) error {
 	var stmt strings.Builder
 	dialect := session.Dialect()
@@ -66,21 +89,44 @@ func CreateTable(session ISession, table Table) error {
 			dialect.Fprintd(&stmt, ", ")
 		}
 	}
+	// Unique indexes are created as constraints, so they come and go with the table
+	if table.Indexes != nil {
+		for _, index := range *table.Indexes {
+			if !index.Unique {
+				continue
+			}
+			columns := make([]string, len(index.Columns))
+			for i, column := range index.Columns {
+				columns[i] = dialect.QuoteIdentifier(column)
+			}
+			dialect.Fprintd(&stmt, ", CONSTRAINT %i UNIQUE (%s)", uniqueName(table, index), strings.Join(columns, ", "))
+		}
+	}
 	dialect.Fprintd(&stmt, ");")
-	fmt.Println(stmt.String())
 	if !sqlsafe.IsSafeSQLString(stmt.String()) {
 		return errors.New("invalid SQL identifier found")
 	}
+
 	statement, err := session.Prepare(stmt.String())
+	if err != nil {
+		return &StatementError{Table: table.Name, Statement: stmt.String(), Err: err}
+	}
+	defer statement.Close()
 
+	_, err = statement.ExecContext(sessionContext(session))
 	if err != nil {
-		log.Fatal(err)
+		return &StatementError{Table: table.Name, Statement: stmt.String(), Err: translateError(session, err)}
 	}
-	_, err = statement.Exec()
+	return nil
+}
+
+// execStatement runs a schema change against table, reporting a failure as a *StatementError
+func execStatement(session ISession, table string, query string) error {
+	_, err := session.Exec(query)
 	if err != nil {
-		log.Fatal("execution error: ", err)
+		return &StatementError{Table: table, Statement: query, Err: err}
 	}
-	return err
+	return nil
 }
 
 func AddColumn(table Table, column Column) Migration {
@@ -92,28 +138,26 @@ func AddColumn(table Table, column Column) Migration {
 			table.Name,
 			column.Name,
 			*column.Type)
-		_, err := db.Exec(query)
+		err := execStatement(db, table.Name, query)
 		if err != nil {
 			return fmt.Errorf("adding column: %w", err)
 		}
 
 		// Create an index if necessary
 		if column.Indexed {
-			query = dialect.Sprintd("CREATE INDEX idx_%i_on_%i ON %i (%i)", table.Name, column.Name, table.Name, column.Name)
-			_, err := db.Exec(query)
+			query = dialect.Sprintd("CREATE INDEX %i ON %i (%i)", indexName(table, column), table.Name, column.Name)
+			err := execStatement(db, table.Name, query)
 			if err != nil {
 				return fmt.Errorf("creating index: %w", err)
 			}
 		}
 
 		// Set as primary key if necessary
-		query = "ALTER TABLE %i ADD COLUMN %i %i"
 		if column.PrimaryKey {
-			query = "ALTER TABLE %i ADD PRIMARY KEY (%i)"
-		}
-		_, err = db.Exec(dialect.Sprintd(query, table.Name, column.Name, *column.Type))
-		if err != nil {
-			return fmt.Errorf("setting primary key: %w", err)
+			err = execStatement(db, table.Name, dialect.Sprintd("ALTER TABLE %i ADD PRIMARY KEY (%i)", table.Name, column.Name))
+			if err != nil {
+				return fmt.Errorf("setting primary key: %w", err)
+			}
 		}
 
 		// Add a foreign key constraint if necessary
@@ -127,7 +171,7 @@ func AddColumn(table Table, column Column) Migration {
 					fk.Table.Name,
 					fk.Column.Name)
 
-				_, err := db.Exec(query)
+				err := execStatement(db, table.Name, query)
 				if err != nil {
 					return fmt.Errorf("setting foreign key: %w", err)
 				}
@@ -141,13 +185,12 @@ func AddColumn(table Table, column Column) Migration {
 func RemoveColumn(column Column) Migration {
 	return func(table Table, db ISession) error {
 		dialect := db.Dialect()
-		_, err := db.Exec(
-			dialect.Sprintd("ALTER TABLE %i DROP COLUMN %i"),
-			table.Name,
-			column.Name)
+		err := execStatement(db, table.Name,
+			dialect.Sprintd("ALTER TABLE %i DROP COLUMN %i",
+				table.Name,
+				column.Name))
 		if err != nil {
-
-			return fmt.Errorf("removing column: %s: %w", table.Name, err)
+			return fmt.Errorf("removing column: %w", err)
 		}
 		return nil
 
@@ -157,43 +200,181 @@ func RemoveColumn(column Column) Migration {
 func ModifyColumn(table Table, oldColumn Column, newColumn Column) Migration {
 	return func(table Table, db ISession) error {
 		dialect := db.Dialect()
+		alterTableColumnName := "ALTER TABLE %i RENAME COLUMN %i TO %i"
+
 		// Put rename and type change SQL commands in a transaction.
-		tx, err := db.Begin()
+		return inTransaction(db, func(tx ISession) error {
+			// The type change applies to the column under its new name, if it has one
+			columnName := oldColumn.Name
+			if newColumn.Name != "" && newColumn.Name != oldColumn.Name {
+				err := execStatement(tx, table.Name,
+					dialect.Sprintd(
+						alterTableColumnName,
+						table.Name,
+						oldColumn.Name,
+						newColumn.Name,
+					),
+				)
+				if err != nil {
+					return fmt.Errorf("renaming column: %w", err)
+				}
+				columnName = newColumn.Name
+			}
+			if newColumn.Type != nil {
+				err := execStatement(tx, table.Name,
+					dialect.AlterColumnType(table.Name, columnName, *newColumn.Type),
+				)
+				if err != nil {
+					return fmt.Errorf("modifying column type: %w", err)
+				}
+			}
+			return nil
+		})
+	}
+}
+
+// AddIndex creates an index on one or more columns. An unnamed index is named after its columns.
+func AddIndex(index Index) Migration {
+	return func(table Table, db ISession) error {
+		dialect := db.Dialect()
+		name := index.Name
+		if name == "" {
+			name = fmt.Sprintf("idx_%s_on_%s", table.Name, strings.Join(index.Columns, "_and_"))
+		}
+
+		columns := make([]string, len(index.Columns))
+		for i, column := range index.Columns {
+			columns[i] = dialect.QuoteIdentifier(column)
+		}
+
+		create := "CREATE INDEX"
+		if index.Unique {
+			create = "CREATE UNIQUE INDEX"
+		}
+		err := execStatement(db, table.Name, dialect.Sprintd(create+" %i ON %i (%s)", name, table.Name, strings.Join(columns, ", ")))
 		if err != nil {
-			return err
+			return fmt.Errorf("creating index %s: %w", name, err)
 		}
-		defer tx.Rollback()
+		return nil
+	}
+}
 
-		alterTableColumnName := "\"ALTER TABLE %i RENAME COLUMN %i TO %i\""
-		alterTableColumnType := "\"ALTER TABLE %i ALTER COLUMN %i TYPE %s\""
+// DropIndex drops a named index
+func DropIndex(index Index) Migration {
+	return func(table Table, db ISession) error {
+		err := execStatement(db, table.Name, db.Dialect().DropIndex(table.Name, index.Name))
+		if err != nil {
+			return fmt.Errorf("dropping index %s: %w", index.Name, err)
+		}
+		return nil
+	}
+}
 
-		if newColumn.Name != "" {
-			_, err = tx.Exec(
-				dialect.Sprintd(
-					alterTableColumnName,
-					table.Name,
-					oldColumn.Name,
-					newColumn.Name,
-				),
-			)
-			if err != nil {
-				return fmt.Errorf("renaming column: %w", err)
-			}
+// AddForeignKey adds the foreign key constraint described by column.ForeignKey to an existing column
+func AddForeignKey(column Column) Migration {
+	return func(table Table, db ISession) error {
+		fk := column.ForeignKey
+		if fk == nil || fk.Table == nil || fk.Column == nil {
+			return fmt.Errorf("column %s has no foreign key", column.Name)
 		}
-		if newColumn.Type != nil {
-			_, err = tx.Exec(
-				dialect.Sprintd(alterTableColumnType, table.Name, newColumn.Name, *newColumn.Type),
-			)
-			if err != nil {
-				return fmt.Errorf("modifying column type: %w", err)
-			}
+
+		query := db.Dialect().Sprintd(
+			"ALTER TABLE %i ADD FOREIGN KEY (%i) REFERENCES %i (%i)",
+			table.Name,
+			column.Name,
+			fk.Table.Name,
+			fk.Column.Name)
+		if fk.Name != "" {
+			query = db.Dialect().Sprintd(
+				"ALTER TABLE %i ADD CONSTRAINT %i FOREIGN KEY (%i) REFERENCES %i (%i)",
+				table.Name,
+				fk.Name,
+				column.Name,
+				fk.Table.Name,
+				fk.Column.Name)
 		}
 
-		err = tx.Commit()
+		err := execStatement(db, table.Name, query)
 		if err != nil {
-			return err
+			return fmt.Errorf("setting foreign key: %w", err)
 		}
+		return nil
+	}
+}
 
+// DropForeignKey drops the named foreign key constraint on column
+func DropForeignKey(column Column) Migration {
+	return func(table Table, db ISession) error {
+		if column.ForeignKey == nil || column.ForeignKey.Name == "" {
+			return fmt.Errorf("column %s has no named foreign key to drop", column.Name)
+		}
+
+		err := execStatement(db, table.Name, db.Dialect().DropForeignKey(table.Name, column.ForeignKey.Name))
+		if err != nil {
+			return fmt.Errorf("dropping foreign key %s: %w", column.ForeignKey.Name, err)
+		}
 		return nil
 	}
 }
+
+// ReversibleAddColumn adds a column on the way up and drops it on the way down.
+func ReversibleAddColumn(table Table, column Column) ReversibleMigration {
+	return ReversibleMigration{
+		Up:   AddColumn(table, column),
+		Down: RemoveColumn(column),
+	}
+}
+
+// ReversibleRemoveColumn drops a column on the way up and adds it back on the way down.
+// The column needs its full definition to be restored; the data it held is not.
+func ReversibleRemoveColumn(table Table, column Column) ReversibleMigration {
+	down := AddColumn(table, column)
+	if column.Type == nil {
+		down = func(table Table, db ISession) error {
+			return fmt.Errorf("cannot restore column %s.%s without its type", table.Name, column.Name)
+		}
+	}
+
+	return ReversibleMigration{
+		Up:   RemoveColumn(column),
+		Down: down,
+	}
+}
+
+// ReversibleModifyColumn renames and/or retypes a column on the way up and restores the old
+// name and type on the way down. oldColumn.Type must be set if newColumn changes the type.
+func ReversibleModifyColumn(table Table, oldColumn Column, newColumn Column) ReversibleMigration {
+	current := Column{Name: oldColumn.Name}
+	if newColumn.Name != "" {
+		current.Name = newColumn.Name
+	}
+	restored := Column{Name: oldColumn.Name}
+	if newColumn.Type != nil {
+		restored.Type = oldColumn.Type
+	}
+
+	down := ModifyColumn(table, current, restored)
+	if newColumn.Type != nil && oldColumn.Type == nil {
+		down = func(table Table, db ISession) error {
+			return fmt.Errorf("cannot restore the type of column %s.%s without its old type", table.Name, oldColumn.Name)
+		}
+	}
+
+	return ReversibleMigration{
+		Up:   ModifyColumn(table, oldColumn, newColumn),
+		Down: down,
+	}
+}
+
+// indexName is the name AddColumn gives the index it creates for an Indexed column
+func indexName(table Table, column Column) string {
+	return fmt.Sprintf("idx_%s_on_%s", table.Name, column.Name)
+}
+
+// uniqueName is the name of a unique index, generated from its columns if it has none
+func uniqueName(table Table, index Index) string {
+	if index.Name != "" {
+		return index.Name
+	}
+	return fmt.Sprintf("uq_%s_on_%s", table.Name, strings.Join(index.Columns, "_"))
+}
@@ -1,11 +1,11 @@
 package data
 
 import (
+	"errors"
 	"fmt"
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/stretchr/testify/assert"
 	"gormless/data/dialect"
-	fixtures "gormless/data/fixtures"
 	"regexp"
 	"testing"
 )
@@ -61,10 +61,6 @@ func TestCreateTable(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			// Create mock objects
-			mockDB := new(fixtures.MockDB)
-			mockStmt := new(fixtures.MockStmt)
-
 			db, mock, err := sqlmock.New()
 			if err != nil {
 				t.Fatalf("Error creating mock database: %v", err)
@@ -91,8 +87,7 @@ func TestCreateTable(t *testing.T) {
 				}
 			} else {
 				assert.NoError(t, err)
-				mockDB.AssertExpectations(t)
-				mockStmt.AssertExpectations(t)
+				assert.NoError(t, mock.ExpectationsWereMet())
 			}
 		})
 	}
@@ -102,3 +97,71 @@ func TestCreateTable(t *testing.T) {
 func stringPtr(s string) *string {
 	return &s
 }
+
+func TestReversibleModifyColumn(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	session := &Session{DB: db}
+	session.SQLDialect = dialect.PostgresDialect{}
+
+	table := Table{Name: "user"}
+	migration := ReversibleModifyColumn(table,
+		Column{Name: "user_first", Type: stringPtr("VARCHAR(32)")},
+		Column{Name: "first_name", Type: stringPtr("VARCHAR(64)")})
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("ALTER TABLE \"user\" RENAME COLUMN \"user_first\" TO \"first_name\"")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("ALTER TABLE \"user\" ALTER COLUMN \"first_name\" TYPE VARCHAR(64)")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("ALTER TABLE \"user\" RENAME COLUMN \"first_name\" TO \"user_first\"")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("ALTER TABLE \"user\" ALTER COLUMN \"user_first\" TYPE VARCHAR(32)")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	assert.NoError(t, migration.Up(table, session))
+	assert.NoError(t, migration.Down(table, session))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReversibleMigrationsNeedOldDefinition(t *testing.T) {
+	table := Table{Name: "user"}
+
+	removal := ReversibleRemoveColumn(table, Column{Name: "status"})
+	assert.ErrorContains(t, removal.Down(table, nil), "cannot restore column user.status without its type")
+
+	retype := ReversibleModifyColumn(table, Column{Name: "status"}, Column{Type: stringPtr("TEXT")})
+	assert.ErrorContains(t, retype.Down(table, nil), "without its old type")
+}
+
+func TestCreateTableReturnsStatementErrors(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	session := &Session{DB: db}
+	session.SQLDialect = dialect.PostgresDialect{}
+	table := testUserTable()
+
+	mock.ExpectPrepare("CREATE TABLE IF NOT EXISTS").WillReturnError(errors.New("connection reset"))
+	mock.ExpectPrepare("CREATE TABLE IF NOT EXISTS").
+		ExpectExec().
+		WillReturnError(errors.New("permission denied for schema public"))
+
+	for _, cause := range []string{"connection reset", "permission denied"} {
+		err = CreateTable(session, table)
+
+		var statementErr *StatementError
+		if assert.ErrorAs(t, err, &statementErr) {
+			assert.Equal(t, "user", statementErr.Table)
+			assert.Contains(t, statementErr.Statement, "CREATE TABLE IF NOT EXISTS \"user\"")
+		}
+		assert.ErrorContains(t, err, cause)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,113 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// WithTx runs fn in a transaction, committing it if fn returns nil and rolling it back if fn
+// returns an error or panics. A panic is re-raised once the transaction is rolled back.
+//
+// tx is an ISession, so DAOs, CreateTable and migrations work inside it as they do outside.
+// Calling WithTx on tx nests a transaction as a SAVEPOINT: if the nested fn fails, only its own
+// changes are undone and the outer transaction carries on.
+//
+// E.g.,
+//
+//	err := session.WithTx(func(tx data.ISession) error {
+//		users := data.DAO[User]{ISession: tx, Table: tables.UserTable()()}
+//		if err := users.Upsert(row); err != nil {
+//			return err
+//		}
+//		return data.UpsertDbVersion(tx, "0002_seed_users", "")
+//	})
+func (s *Session) WithTx(fn func(tx ISession) error) error {
+	return runTx(context.Background(), s, nil, fn)
+}
+
+// WithTx nests a transaction inside this one as a SAVEPOINT. See Session.WithTx.
+func (s *TxSession) WithTx(fn func(tx ISession) error) error {
+	return runTx(context.Background(), s, nil, fn)
+}
+
+// WithTx runs fn in a transaction that is begun, or whose savepoint is set, under the session's
+// context. tx is bound to the same context.
+func (s *contextSession) WithTx(fn func(tx ISession) error) error {
+	return runTx(s.ctx, s, nil, fn)
+}
+
+// runTx begins a transaction on session, or sets a savepoint if session is already in one, and
+// runs fn in it
+func runTx(ctx context.Context, session ISession, opts *sql.TxOptions, fn func(tx ISession) error) error {
+	if tx, ok := unwrapSession(session).(*TxSession); ok {
+		return tx.savepoint(ctx, session, fn)
+	}
+
+	sqlTx, err := session.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	return finishTx(fn, bindLike(session, &TxSession{Tx: sqlTx, parent: session}),
+		func() error { return translateError(session, sqlTx.Commit()) }, // Deferred constraints are checked here
+		func() { _ = sqlTx.Rollback() })
+}
+
+// savepoint runs fn nested in the transaction under a SAVEPOINT, releasing it if fn succeeds and
+// rolling back to it if fn fails. session is s, possibly bound to ctx.
+func (s *TxSession) savepoint(ctx context.Context, session ISession, fn func(tx ISession) error) error {
+	d := s.Dialect()
+	name := fmt.Sprintf("savepoint_%d", s.depth+1)
+
+	_, err := s.Tx.ExecContext(ctx, d.Sprintd("SAVEPOINT %i", name))
+	if err != nil {
+		return fmt.Errorf("setting savepoint %s: %w", name, err)
+	}
+
+	nested := &TxSession{Tx: s.Tx, parent: s.parent, depth: s.depth + 1}
+	return finishTx(fn, bindLike(session, nested),
+		func() error {
+			_, err := s.Tx.ExecContext(ctx, d.Sprintd("RELEASE SAVEPOINT %i", name))
+			if err != nil {
+				return fmt.Errorf("releasing savepoint %s: %w", name, err)
+			}
+			return nil
+		},
+		func() { _, _ = s.Tx.ExecContext(ctx, d.Sprintd("ROLLBACK TO SAVEPOINT %i", name)) })
+}
+
+// finishTx runs fn on tx, then commits if it succeeds and rolls back if it fails or panics
+func finishTx(fn func(tx ISession) error, tx ISession, commit func() error, rollback func()) error {
+	panicked := true
+	defer func() {
+		if panicked {
+			rollback()
+		}
+	}()
+
+	err := fn(tx)
+	panicked = false
+	if err != nil {
+		rollback()
+		return err
+	}
+	return commit()
+}
+
+// bindLike binds tx to the context session was bound to by WithContext, if any
+func bindLike(session ISession, tx ISession) ISession {
+	if s, ok := session.(*contextSession); ok {
+		return WithContext(s.ctx, tx)
+	}
+	return tx
+}
+
+// inTransaction runs fn inside a transaction, committing if it succeeds and rolling back if it fails.
+// If session is already a transaction, fn runs in it and the caller keeps control of the outcome.
+func inTransaction(session ISession, fn func(tx ISession) error) error {
+	if _, ok := unwrapSession(session).(*TxSession); ok {
+		return fn(session)
+	}
+	return session.WithTx(fn)
+}
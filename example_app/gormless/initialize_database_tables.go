@@ -4,8 +4,18 @@ import (
 	"fmt"
 	"gormless/data"
 	"gormless/example_app/gormless/tables"
+	"log"
+	"time"
 )
 
+// initLock keeps replicas that start at the same time from creating tables concurrently.
+// A replica that can't get the lock within the timeout leaves initialization to the one holding it.
+var initLock = data.LockOptions{
+	Name:           "gormless_example_init",
+	Timeout:        30 * time.Second,
+	SkipWhenLocked: true,
+}
+
 func InitializeDatabaseTables() {
 	session, err := GetSession()
 	defer func() {
@@ -14,17 +24,30 @@ func InitializeDatabaseTables() {
 	if err != nil {
 		panic("Couldn't get DB session: " + err.Error())
 	}
-	err = data.InitDatabaseVersion(session)
+
+	ran, err := data.WithLock(session, initLock, func() error {
+		return initializeDatabaseTables(session)
+	})
 	if err != nil {
-		panic("Couldn't init DB: " + err.Error())
+		log.Printf("Couldn't initialize the database: %v", err)
+		return
+	}
+	if !ran {
+		fmt.Println("Another process is initializing the database; skipping")
 	}
-	
+}
+
+func initializeDatabaseTables(session data.ISession) error {
+	err := data.InitDatabaseVersion(session)
+	if err != nil {
+		return fmt.Errorf("Couldn't init DB: %w", err)
+	}
+
 	fmt.Println("Creating UserRole Table")
 	initUserRole := tables.InitUserRoleTable(session)
 	err = initUserRole(tables.UserRoleTable())
 	if err != nil {
-		println("Couldn't create UserRole table:", err.Error())
-		return
+		return fmt.Errorf("Couldn't create UserRole table: %w", err)
 	}
 	fmt.Println("Creating User Table")
 
@@ -33,6 +56,7 @@ func InitializeDatabaseTables() {
 	err = initUser(tables.UserTable())
 
 	if err != nil {
-		err = fmt.Errorf("Couldn't create User table: %v", err)
+		return fmt.Errorf("Couldn't create User table: %w", err)
 	}
+	return nil
 }
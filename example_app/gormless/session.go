@@ -17,7 +17,7 @@ func GetSession() (*data.Session, error) {
 		conf.Database.DBName,
 		conf.Database.SSLMode)
 
-	session, err := data.GetDbSession(dsn, dialect.POSTGRES)
+	session, err := data.GetDbSession(dsn, dialect.POSTGRES, conf.Database.Pool)
 	if err != nil {
 		return session, err
 	}
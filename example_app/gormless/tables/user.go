@@ -4,8 +4,6 @@ import (
 	"fmt"
 	data "gormless/data"
 	dialect "gormless/data/dialect"
-
-	"log"
 )
 
 func UserTable() data.TableDef {
@@ -45,13 +43,13 @@ func InitUserTable(session data.ISession) data.TableInitializer {
 	return func(def data.TableDef) error {
 		err := session.Ping()
 		if err != nil {
-			log.Fatal(err)
+			return fmt.Errorf("Failed to reach the database: %w", err)
 		}
 
 		userTable := UserTable()
 		err = data.CreateTable(session, userTable())
 		if err != nil {
-			return fmt.Errorf("Failed to create User table: %v", err)
+			return fmt.Errorf("Failed to create User table: %w", err)
 		}
 		return err
 	}
@@ -63,7 +61,7 @@ func InitUserTableWithNewSession(dsn) data.TableInitializer {
 	return func(def data.TableDef) error {
 		err := session.Ping()
 		if err != nil {
-			log.Fatal(err)
+			return fmt.Errorf("Failed to reach the database: %w", err)
 		}
 
 		userTable := UserTable()
@@ -1,12 +1,16 @@
 package tables
 
 import (
+	"errors"
 	"fmt"
 	"gormless/data"
 	"gormless/data/dialect"
-	"log"
 )
 
+// uniqueRoleNameVersion gives user_role tables created before role names were unique the
+// constraint that seeding the default roles relies on
+const uniqueRoleNameVersion = "0001_user_role_unique_role_name"
+
 func UserRoleTable() data.TableDef {
 	roleIdType := dialect.PsqlSmallSerial
 	roleNameType := fmt.Sprintf(dialect.PsqlVarChar, 32)
@@ -18,24 +22,82 @@ func UserRoleTable() data.TableDef {
 				{Name: "role_id", PrimaryKey: true, Type: &roleIdType},
 				{Name: "role_name", Type: &roleNameType},
 			},
+			Indexes: &[]data.Index{
+				{Name: "uq_user_role_on_role_name", Columns: []string{"role_name"}, Unique: true},
+			},
 		}
 		return userRoleTable
 	}
 }
 
-func InitUserRoleTable(session data.ISession) data.TableInitializer {
-	err := session.Ping()
+// Migrations returns every migration of the example app's tables. Its versions share the version
+// table with the tables themselves, so plan, migrate and roll back with this registry: one that
+// leaves a version out fails on it as not registered.
+func Migrations() (*data.MigrationRegistry, error) {
+	registry := data.NewMigrationRegistry()
+	table := UserRoleTable()()
+	err := registry.Register(uniqueRoleNameVersion, table, uniqueRoleName)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
+	return registry, nil
+}
 
+// uniqueRoleName removes the duplicate roles older releases seeded on every start, pointing users
+// at the first copy of each, then adds the unique index on role_name.
+//
+// user_role is set up before user, so the user table may not exist yet. The database checks for it,
+// keeping the migration's SQL, and so its checksum, the same either way.
+func uniqueRoleName(table data.Table, session data.ISession) error {
+	d := session.Dialect()
+	_, err := session.Exec(d.Sprintd(
+		"DO $$ BEGIN IF to_regclass('%i') IS NOT NULL THEN "+
+			"UPDATE %i SET %i = (SELECT MIN(k.%i) FROM %i r JOIN %i k ON k.%i = r.%i WHERE r.%i = %i.%i) WHERE %i IS NOT NULL; "+
+			"END IF; END $$",
+		"user",
+		"user", "user_role", "role_id", table.Name, table.Name, "role_name", "role_name", "role_id", "user", "user_role", "user_role"))
+	if err != nil {
+		return fmt.Errorf("pointing users at the first copy of each role: %w", err)
+	}
+
+	_, err = session.Exec(d.Sprintd(
+		"DELETE FROM %i r USING %i k WHERE r.%i = k.%i AND r.%i > k.%i",
+		table.Name, table.Name, "role_name", "role_name", "role_id", "role_id"))
+	if err != nil {
+		return fmt.Errorf("removing duplicate roles: %w", err)
+	}
+
+	return data.AddIndex(data.Index{Name: "uq_user_role_on_role_name", Columns: []string{"role_name"}, Unique: true})(table, session)
+}
+
+func InitUserRoleTable(session data.ISession) data.TableInitializer {
 	return func(def data.TableDef) error {
+		err := session.Ping()
+		if err != nil {
+			return fmt.Errorf("Failed to reach the database: %w", err)
+		}
+
 		// Create the table structure
 		userRoleTable := UserRoleTable()
 		table := userRoleTable()
+		_, err = data.IntrospectTable(session, table.Name)
+		existing := !errors.Is(err, data.ErrTableNotFound)
+		if err != nil && existing {
+			return fmt.Errorf("Failed to read user_role table: %w", err)
+		}
 		err = data.CreateTable(session, table)
 		if err != nil {
-			return fmt.Errorf("Failed to create user_role table: %v", err)
+			return fmt.Errorf("Failed to create user_role table: %w", err)
+		}
+
+		// A table created now already has the unique constraint; older ones are migrated to it
+		if existing {
+			err = migrateUserRoleTable(session)
+		} else {
+			err = markUserRoleTableMigrated(session, table)
+		}
+		if err != nil {
+			return err
 		}
 
 		// Create a generic DAO for inserting default values
@@ -51,12 +113,39 @@ func InitUserRoleTable(session data.ISession) data.TableInitializer {
 			{"role_name": "guest"},
 		}
 
-		// Insert all default roles at once
-		err = dao.Upsert(defaultRoles...)
+		// Insert all default roles at once, leaving any that already exist alone
+		err = dao.UpsertWith(data.UpsertOptions{Target: []string{"role_name"}, DoNothing: true}, defaultRoles...)
 		if err != nil {
-			return fmt.Errorf("Failed to insert default roles: %v", err)
+			return fmt.Errorf("Failed to insert default roles: %w", err)
 		}
 
 		return nil
 	}
 }
+
+// migrateUserRoleTable applies the migrations an existing table hasn't had yet
+func migrateUserRoleTable(session data.ISession) error {
+	registry, err := Migrations()
+	if err != nil {
+		return err
+	}
+	_, err = data.NewMigrationRunner(session, registry).Migrate()
+	if err != nil {
+		return fmt.Errorf("Failed to migrate user_role table: %w", err)
+	}
+	return nil
+}
+
+// markUserRoleTableMigrated records the user_role migrations as applied to a table created with
+// everything they add
+func markUserRoleTableMigrated(session data.ISession, table data.Table) error {
+	checksum, err := data.MigrationChecksum(session.Dialect(), table, uniqueRoleName)
+	if err != nil {
+		return err
+	}
+	err = data.UpsertDbVersion(session, uniqueRoleNameVersion, checksum)
+	if err != nil {
+		return fmt.Errorf("Failed to record user_role migrations: %w", err)
+	}
+	return nil
+}
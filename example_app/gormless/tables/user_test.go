@@ -1,6 +1,7 @@
 package tables
 
 import (
+	"errors"
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/stretchr/testify/assert"
 	"gormless/data"
@@ -54,15 +55,24 @@ func TestInitUserRoleTable(t *testing.T) {
 	// Set expectations for the database operations
 	mock.ExpectPing()
 
+	// The table doesn't exist yet
+	mock.ExpectQuery(regexp.QuoteMeta(session.SQLDialect.ColumnsQuery())).WithArgs("user_role").
+		WillReturnRows(sqlmock.NewRows([]string{"attname", "format_type", "auto_increment"}))
+
 	// Expect the CREATE TABLE statement
-	expectedCreateSQL := regexp.QuoteMeta("CREATE TABLE IF NOT EXISTS \"user_role\" (\"role_id\" SMALLSERIAL PRIMARY KEY, \"role_name\" VARCHAR(32));")
+	expectedCreateSQL := regexp.QuoteMeta("CREATE TABLE IF NOT EXISTS \"user_role\" (\"role_id\" SMALLSERIAL PRIMARY KEY, \"role_name\" VARCHAR(32), CONSTRAINT \"uq_user_role_on_role_name\" UNIQUE (\"role_name\"));")
 	mock.ExpectPrepare(expectedCreateSQL).
 		ExpectExec().
 		WillReturnResult(sqlmock.NewResult(0, 0))
 
+	// It is created with the unique constraint, so the migration adding it is recorded as applied
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO \"version\"")).
+		WithArgs(sqlmock.AnyArg(), uniqueRoleNameVersion, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
 	// Expect the INSERT statement with multiple rows
 	mock.ExpectExec(regexp.QuoteMeta(
-		"INSERT INTO \"user_role\" (\"role_name\") VALUES ($1), ($2), ($3) ON CONFLICT (\"role_id\") DO UPDATE SET \"role_name\" = EXCLUDED.\"role_name\"")).
+		"INSERT INTO \"user_role\" (\"role_name\") VALUES ($1), ($2), ($3) ON CONFLICT (\"role_name\") DO NOTHING")).
 		WithArgs("admin", "user", "guest").
 		WillReturnResult(sqlmock.NewResult(3, 3))
 
@@ -79,6 +89,54 @@ func TestInitUserRoleTable(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestInitUserRoleTableMigratesExistingTable(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	session := &data.Session{DB: db}
+	session.SQLDialect = dialect.PostgresDialect{}
+	d := session.SQLDialect
+
+	// The table was created by an older release, without the unique constraint
+	mock.ExpectQuery(regexp.QuoteMeta(d.ColumnsQuery())).WithArgs("user_role").
+		WillReturnRows(sqlmock.NewRows([]string{"attname", "format_type", "auto_increment"}).
+			AddRow("role_id", "smallint", true).
+			AddRow("role_name", "character varying(32)", false))
+	mock.ExpectQuery(regexp.QuoteMeta(d.PrimaryKeyQuery())).WithArgs("user_role").
+		WillReturnRows(sqlmock.NewRows([]string{"column_name"}).AddRow("role_id"))
+	mock.ExpectQuery(regexp.QuoteMeta(d.IndexesQuery())).WithArgs("user_role").
+		WillReturnRows(sqlmock.NewRows([]string{"relname", "attname", "indisunique"}))
+	mock.ExpectQuery(regexp.QuoteMeta(d.ForeignKeysQuery())).WithArgs("user_role").
+		WillReturnRows(sqlmock.NewRows([]string{"column_name", "table_name", "column_name", "constraint_name"}))
+	mock.ExpectPrepare("CREATE TABLE IF NOT EXISTS").
+		ExpectExec().
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	// The constraint is added before the seed needs it
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT \"database_version\", \"version_date\", \"checksum\" FROM \"version\"")).
+		WillReturnRows(sqlmock.NewRows([]string{"database_version", "version_date", "checksum"}))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("DO $$ BEGIN IF to_regclass('\"user\"') IS NOT NULL THEN UPDATE \"user\" SET \"user_role\"")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM \"user_role\" r USING \"user_role\" k")).WillReturnResult(sqlmock.NewResult(0, 6))
+	mock.ExpectExec(regexp.QuoteMeta("CREATE UNIQUE INDEX \"uq_user_role_on_role_name\" ON \"user_role\" (\"role_name\")")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO \"version\"")).
+		WithArgs(sqlmock.AnyArg(), uniqueRoleNameVersion, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec(regexp.QuoteMeta("ON CONFLICT (\"role_name\") DO NOTHING")).
+		WithArgs("admin", "user", "guest").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = InitUserRoleTable(session)(UserRoleTable())
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 // TestUserTableIntegration is an integration test that requires a real database
 // This test is disabled by default (remove the leading underscore to enable)
 func _TestUserTableIntegration(t *testing.T) {
@@ -92,7 +150,7 @@ func _TestUserTableIntegration(t *testing.T) {
 	dsn := "user=evan dbname=gotest sslmode=disable"
 
 	// Get a real database connection
-	session, err := data.GetDbSession(dsn, "postgres")
+	session, err := data.GetDbSession(dsn, "postgres", data.Pool{})
 	if err != nil {
 		t.Fatalf("Failed to connect to database: %v", err)
 	}
@@ -130,3 +188,23 @@ func _TestUserTableIntegration(t *testing.T) {
 	_, err = session.Exec("DROP TABLE IF EXISTS \"user_role\"")
 	assert.NoError(t, err)
 }
+
+func TestInitializersReturnErrors(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	if err != nil {
+		t.Fatalf("Error creating mock database: %v", err)
+	}
+	defer db.Close()
+
+	session := &data.Session{DB: db}
+	session.SQLDialect = dialect.PostgresDialect{}
+
+	mock.ExpectPing().WillReturnError(errors.New("connection refused"))
+	mock.ExpectPing().WillReturnError(errors.New("connection refused"))
+
+	err = InitUserRoleTable(session)(UserRoleTable())
+	assert.ErrorContains(t, err, "connection refused")
+	err = InitUserTable(session)(UserTable())
+	assert.ErrorContains(t, err, "connection refused")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
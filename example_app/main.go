@@ -20,7 +20,7 @@ func Main() {
 		panic("Couldn't get DB session: " + err.Error())
 	}
 	println(fmt.Sprintf("Hello World %s", time.Now()))
-	newUser, err := user.NewUser(session, "john@example.com", "John", "Doe", user.UserRole{RoleName: user.RoleAdmin})
+	newUser, err := user.NewUser(session, "john@example.com", "John", "Doe", user.Role{RoleName: user.RoleAdmin})
 	if err != nil {
 		return
 	}
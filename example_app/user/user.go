@@ -1,14 +1,14 @@
 package user
 
 import (
-	"database/sql"
 	"fmt"
 	"gormless/data"
 	"gormless/example_app/gormless/tables"
 )
 
 type Role struct {
-	RoleName string
+	RoleID   int    `db:"role_id"`
+	RoleName string `db:"role_name"`
 }
 
 const (
@@ -18,10 +18,12 @@ const (
 )
 
 type User struct {
-	FirstName string
-	LastName  string
-	Email     string
-	Role      string
+	ID        int    `db:"user_id"`
+	FirstName string `db:"user_first"`
+	LastName  string `db:"user_last"`
+	Email     string `db:"user_email"`
+	RoleID    int    `db:"user_role"`
+	Role      string `db:"-"`
 }
 
 type DAO struct {
@@ -87,28 +89,16 @@ func InsertUserRoles(session data.ISession) error {
 	return nil
 }
 
-func (d *DAO) GetUsersByColumn(column string, value any) (*sql.Rows, error) {
-
-	rows, err := d.GetMany(column, value, false)
-	return rows, err
+func (d *DAO) GetUsersByColumn(column string, value any) ([]User, error) {
+	return d.FindMany(column, value, false)
 }
 
-func (user *User) GetUserByEmail() (*sql.Row, error) {
-
-	column := "user_email"
-	row, err := user.GetUserByColumn(column, user.Email)
-	if err != nil {
-		return nil, err
-	}
-	return row, err
+func (d *DAO) GetUserByEmail(email string) (User, error) {
+	return d.FindOne("user_email", email)
 }
 
-func (user *User) GetUsersByRole() (*[]User, error) {
-
-	rows, err := user.GetUsersByColumn("user_role", user.Role)
-	if err != nil {
-	}
-	return rows, err
+func (d *DAO) GetUsersByRole(role Role) ([]User, error) {
+	return d.GetUsersByColumn("user_role", role.RoleID)
 }
 
 func isValidRole(role string) bool {
@@ -130,19 +120,15 @@ func NewUser(session data.ISession, user_email string, user_first string, user_l
 		Email:     user_email,
 		FirstName: user_first,
 		LastName:  user_last,
+		RoleID:    user_role.RoleID,
 		Role:      user_role.RoleName,
 	}
 
 	dao := NewUserDAO(session)
-	dao.Table.Columns = &[]data.Column{
-		{Name: "user_first", Type: &user.FirstName},
-		{Name: "user_last", Type: &user.LastName},
-		{Name: "user_email", Type: &user.Email, Indexed: true},
-		{Name: "user_role", Type: &user.Role},
-	}
-	err := dao.Upsert()
+	created, err := dao.InsertStructReturning(*user)
 	if err != nil {
 		return nil, err
 	}
-	return user, nil
+	created[0].Role = user.Role
+	return &created[0], nil
 }
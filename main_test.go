@@ -39,6 +39,8 @@ func TestMainFunction(t *testing.T) {
 	// Set up expectations
 	mock.ExpectPing()
 	mock.ExpectPrepare("CREATE TABLE IF NOT EXISTS").ExpectExec().WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT").WithArgs("version").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "type", "auto_increment"}).AddRow("checksum", "character(64)", false))
 
 	// Call function under test
 	err = data.InitDatabaseVersion(session)